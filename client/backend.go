@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// backendVersionTimeout 查询后端版本的时间上限
+const backendVersionTimeout = 5 * time.Second
+
+// ArchiveBackend 压缩包操作后端
+// 测试、列出和解压都通过该接口完成，具体由内置的 7z.exe、系统中的 7z 还是其他实现处理对调用方透明
+type ArchiveBackend interface {
+	// Test 用密码测试压缩包（entry 非空时只测试该文件），返回结果类型
+	Test(ctx context.Context, archivePath, password, entry string) SevenZipResult
+	// List 列出压缩包内的文件（文件名加密时需要密码）
+	List(ctx context.Context, archivePath, password string) ([]archiveEntry, SevenZipResult)
+	// Extract 把压缩包解压到 extractPath，失败时返回 *archiveError；onProgress 非空时回调解压进度
+	Extract(ctx context.Context, archivePath, password, extractPath string, onProgress ProgressFunc) error
+	// Version 后端名称和版本，用于显示
+	Version() string
+}
+
+// sevenZipBackend 调用 7-Zip 命令行的后端
+// Windows 上是内置并释放到临时目录的 7z.exe，其他系统上是 PATH 中的 7zz/7z/7za（见 sevenzip*.go）
+type sevenZipBackend struct {
+	path string // 7z 可执行文件路径
+	name string // 显示名称：「内置」或「系统」
+}
+
+// sevenZip 当前系统的 7z 后端
+var sevenZip = newSevenZipBackend()
+
+// getSevenZipPath 获取 7z 可执行文件路径
+func getSevenZipPath() string {
+	return sevenZip.path
+}
+
+// 函数说明：选择处理该压缩包的后端
+// 无需密码的 tar/gz/bz2/xz 和未加密的 zip 用纯 Go 后端（不支持的情况再交给 7z），其余用 7z
+// 参数：
+// archivePath: 压缩文件路径
+// 返回：后端
+func selectArchiveBackend(archivePath string) ArchiveBackend {
+	if canExtractNatively(archivePath) {
+		return &fallbackBackend{primary: nativeBackend{}, fallback: sevenZip}
+	}
+	return sevenZip
+}
+
+// 函数说明：运行 7z 命令
+// 标准输出与错误输出分开收集（-bso1 -bse2），关闭进度输出（-bsp0），再按退出码和错误输出归类结果
+// 参数：
+// ctx: 上下文（超时或取消时终止 7z 进程，结果为 RESULT_UNKNOWN）
+// args: 7z 参数，第一个为命令（t/l/x 等）
+// 返回：7z 输出及结果类型
+func (b *sevenZipBackend) run(ctx context.Context, args ...string) sevenZipOutput {
+	return b.runWithOutput(ctx, nil, args...)
+}
+
+// 函数说明：运行 7z 命令，onOutput 非空时打开进度和文件日志输出（-bsp1 -bb1），
+// 标准输出按 \r、\n、\b 切分后逐段回调（进度行靠回车或退格覆盖刷新）
+// 参数：
+// ctx: 上下文（超时或取消时终止 7z 进程，结果为 RESULT_UNKNOWN）
+// onOutput: 标准输出回调，为空时与 run 相同
+// args: 7z 参数，第一个为命令（t/l/x 等）
+// 返回：7z 输出及结果类型
+func (b *sevenZipBackend) runWithOutput(ctx context.Context, onOutput func(segment string), args ...string) sevenZipOutput {
+	outputArgs := []string{"-bso1", "-bse2", "-bsp0"}
+	if onOutput != nil {
+		outputArgs = []string{"-bso1", "-bse2", "-bsp1", "-bb1"}
+	}
+	fullArgs := append(append([]string{args[0]}, outputArgs...), args[1:]...)
+	cmd := exec.CommandContext(ctx, b.path, fullArgs...)
+	cmd.Env = append(os.Environ(), "LANG=C.UTF-8")
+	// 取消时结束 7z 及其子进程，不留下孤儿进程
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessTree(cmd.Process) }
+	cmd.WaitDelay = processWaitDelay
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	var err error
+	if onOutput == nil {
+		err = cmd.Run()
+	} else {
+		pr, pw := io.Pipe()
+		cmd.Stdout = pw
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			scanner := bufio.NewScanner(io.TeeReader(pr, &stdout))
+			scanner.Split(scanProgressSegments)
+			for scanner.Scan() {
+				if segment := strings.TrimSpace(scanner.Text()); segment != "" {
+					onOutput(segment)
+				}
+			}
+			// 回调提前结束时继续读完，避免 7z 写满管道后阻塞
+			io.Copy(io.Discard, pr)
+		}()
+		err = cmd.Run()
+		pw.Close()
+		<-done
+	}
+
+	out := sevenZipOutput{
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: EXIT_OK,
+	}
+
+	// 被取消或超时：进程已被终止，退出码没有意义
+	if ctx.Err() != nil {
+		out.ExitCode = -1
+		out.Result = RESULT_UNKNOWN
+		return out
+	}
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			// 7z 无法启动（被杀毒软件拦截、文件缺失、系统中未安装等）
+			out.ExitCode = -1
+			out.Stderr += err.Error()
+			out.Result = RESULT_FAILED
+			return out
+		}
+		out.ExitCode = exitErr.ExitCode()
+	}
+
+	out.Result = classify7zResult(out.ExitCode, out.Stderr)
+	return out
+}
+
+// Test 使用7z的t命令测试文件完整性（CRC 校验通过即密码正确）
+func (b *sevenZipBackend) Test(ctx context.Context, archivePath, password, entry string) SevenZipResult {
+	args := []string{
+		"t",
+		format7zPasswordArg(password),
+		"-spd", // 文件名按原样匹配，不作通配符解析
+		archivePath,
+	}
+	if entry != "" {
+		args = append(args, "--", entry)
+	}
+	return b.run(ctx, args...).Result
+}
+
+// List 使用 7z l -slt 列出压缩包内的文件
+func (b *sevenZipBackend) List(ctx context.Context, archivePath, password string) ([]archiveEntry, SevenZipResult) {
+	out := b.run(ctx,
+		"l",
+		"-slt",
+		"-sccUTF-8",
+		format7zPasswordArg(password),
+		archivePath,
+	)
+	if out.Result != RESULT_OK {
+		return nil, out.Result
+	}
+	return parseSltListing(out.Stdout), RESULT_OK
+}
+
+// Extract 使用 7z x 解压（保留目录结构，覆盖同名文件）
+// 需要进度时先列出文件求出解压后的总大小，再按 7z 输出的百分比估算已解压的字节数
+func (b *sevenZipBackend) Extract(ctx context.Context, archivePath, password, extractPath string, onProgress ProgressFunc) error {
+	args := []string{
+		"x",
+		"-y",
+		"-sccUTF-8",
+		format7zPasswordArg(password),
+		fmt.Sprintf("-o%s", extractPath),
+		archivePath,
+	}
+
+	var onOutput func(string)
+	var finish func()
+	if onProgress != nil {
+		var progress ExtractProgress
+		if entries, result := b.List(ctx, archivePath, password); result == RESULT_OK {
+			for _, e := range entries {
+				if !e.IsDir {
+					progress.Total += e.Size
+				}
+			}
+		}
+		throttle := newProgressThrottle(onProgress)
+		onOutput = func(segment string) {
+			if parseSevenZipProgress(segment, &progress) {
+				throttle.report(progress, false)
+			}
+		}
+		finish = func() {
+			progress.Percent, progress.Bytes = 100, progress.Total
+			throttle.report(progress, true)
+		}
+	}
+
+	out := b.runWithOutput(ctx, onOutput, args...)
+	if out.Result != RESULT_OK {
+		return &archiveError{Result: out.Result, Detail: out.errorDetail()}
+	}
+	if finish != nil {
+		finish()
+	}
+	return nil
+}
+
+// Version 7z 不带参数运行时输出的第一行，如 "7-Zip (z) 24.09 (x64) : Copyright ..."
+func (b *sevenZipBackend) Version() string {
+	ctx, cancel := context.WithTimeout(context.Background(), backendVersionTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, b.path).Output()
+	if err != nil && len(output) == 0 {
+		return fmt.Sprintf("%s（无法运行 %s: %v）", b.name, b.path, err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			if version, _, ok := strings.Cut(line, " : "); ok {
+				line = version
+			}
+			return fmt.Sprintf("%s %s", b.name, line)
+		}
+	}
+	return b.name + " " + strconv.Quote(b.path)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"hash/fnv"
+	"math"
+	"os"
+)
+
+// 布隆过滤器大小限制：去重内存不随字典大小无限增长
+const (
+	bloomMinBits        = 1 << 20 // 最小 128KB
+	bloomMaxBits        = 1 << 31 // 最大 256MB
+	bloomFalsePositive  = 1e-7    // 目标误判率（误判只多占一点内存，不会漏掉密码）
+	bloomMaxHashes      = 16
+	bloomBytesPerRecord = 4 // 按平均每行 4 字节估计记录数（偏多估计，保证误判率）
+)
+
+// dedupMaxSuspects 精确去重时最多记录的可能重复的密码数，超过时不去重（避免占用过多内存）
+const dedupMaxSuspects = 1 << 22
+
+// errTooManySuspects 可能重复的密码超过 dedupMaxSuspects
+var errTooManySuspects = errors.New("可能重复的密码过多")
+
+// bloomFilter 固定内存的布隆过滤器，用于找出大字典中可能重复的密码
+// 哈希不带随机种子，同样的输入每次得到同样的结果，保证续跑时候选密码的位置不变
+type bloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes int
+}
+
+// 函数说明：按预计的数据量创建布隆过滤器
+// 参数：
+// dataSize: 字典文件的总字节数
+// 返回：布隆过滤器
+func newBloomFilter(dataSize int64) *bloomFilter {
+	n := float64(dataSize/bloomBytesPerRecord + 1)
+	size := uint64(-n * math.Log(bloomFalsePositive) / (math.Ln2 * math.Ln2))
+	size = min(max(size, bloomMinBits), bloomMaxBits)
+
+	hashes := int(math.Round(float64(size) / n * math.Ln2))
+	hashes = min(max(hashes, 1), bloomMaxHashes)
+
+	return &bloomFilter{
+		bits:   make([]uint64, size/64),
+		size:   size / 64 * 64,
+		hashes: hashes,
+	}
+}
+
+// testAndAdd 加入一个字符串，返回加入前是否（可能）已存在
+func (b *bloomFilter) testAndAdd(s string) bool {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	sum := h.Sum64()
+	// FNV 对短字符串的低位区分度差，再混合一遍；两个哈希值组合出 k 个位置
+	h1 := mix64(sum)
+	h2 := mix64(sum^0x9e3779b97f4a7c15) | 1
+
+	exists := true
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % b.size
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if b.bits[word]&mask == 0 {
+			exists = false
+			b.bits[word] |= mask
+		}
+	}
+	return exists
+}
+
+// mix64 64 位整数的混合函数（splitmix64 的收尾步骤）
+func mix64(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// 函数说明：找出可能重复的密码：布隆过滤器判断为已出现过的都记下来
+// 真正重复的密码第二次出现时一定会被记下，没被记下的密码一定只出现一次；
+// 这样只需为重复（和极少数误判）的密码占用内存
+// 参数：
+// paths: 按顺序读取的密码文件
+// limit: 最多记录的数量，超过时返回 errTooManySuspects；<=0 时不限制
+// 返回：可能重复的密码集合，错误信息
+func findDuplicateSuspects(paths []string, limit int) (map[string]bool, error) {
+	return findDuplicates(paths, limit, forEachPassword)
+}
+
+// findDuplicateLines 找出可能重复的行（原样比较，保留首尾空白），修改字典时使用
+func findDuplicateLines(paths []string) (map[string]bool, error) {
+	return findDuplicates(paths, 0, forEachDictLine)
+}
+
+// findDuplicates 按 forEach 读出的每一项找出可能重复的项
+func findDuplicates(paths []string, limit int, forEach func(path string, fn func(string)) error) (map[string]bool, error) {
+	var dataSize int64
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			dataSize += estimatePasswordDataSize(path, info.Size())
+		}
+	}
+	bloom := newBloomFilter(dataSize)
+	suspects := make(map[string]bool)
+	tooMany := false
+	for _, path := range paths {
+		err := forEach(path, func(password string) {
+			if tooMany || !bloom.testAndAdd(password) {
+				return
+			}
+			suspects[password] = true
+			tooMany = limit > 0 && len(suspects) > limit
+		})
+		if err != nil {
+			return nil, err
+		}
+		if tooMany {
+			return nil, errTooManySuspects
+		}
+	}
+	return suspects, nil
+}
+
+// dedupSet 精确去重：只为可能重复的密码记录是否已出现过，其余密码只出现一次，直接放行
+type dedupSet struct {
+	suspects map[string]bool // 可能重复的密码，nil 时不去重
+	seen     map[string]bool
+}
+
+func newDedupSet(suspects map[string]bool) *dedupSet {
+	return &dedupSet{suspects: suspects, seen: make(map[string]bool)}
+}
+
+// duplicate 密码此前是否已出现过（第一次出现时记下）
+func (d *dedupSet) duplicate(password string) bool {
+	if !d.suspects[password] {
+		return false
+	}
+	if d.seen[password] {
+		return true
+	}
+	d.seen[password] = true
+	return false
+}
+
+// reset 从头开始去重
+func (d *dedupSet) reset() {
+	clear(d.seen)
+}
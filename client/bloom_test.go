@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 按实际字典的规模（每行约 10 字节）检查误判率
+func TestBloomFalsePositiveRate(t *testing.T) {
+	sizes := []int{100_000, 1_000_000}
+	if !testing.Short() {
+		sizes = append(sizes, 5_000_000)
+	}
+	for _, n := range sizes {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			bloom := newBloomFilter(int64(n) * 11)
+			for i := 0; i < n; i++ {
+				bloom.testAndAdd(fmt.Sprintf("pw%08d", i))
+			}
+			falsePositives := 0
+			for i := 0; i < n; i++ {
+				if bloom.testAndAdd(fmt.Sprintf("xx%08d", i)) {
+					falsePositives++
+				}
+			}
+			rate := float64(falsePositives) / float64(n)
+			if rate > 1e-5 {
+				t.Errorf("%d 个密码时误判率 %.2g（%d 个），超过 1e-5", n, rate, falsePositives)
+			}
+		})
+	}
+}
+
+// 布隆过滤器误判（只出现一次的密码被当成可能重复）时，dedupSet 也不能跳过它
+func TestDedupSetKeepsFalsePositives(t *testing.T) {
+	suspects := map[string]bool{"dup": true, "once": true}
+	dedup := newDedupSet(suspects)
+	var kept []string
+	for _, password := range []string{"a", "dup", "once", "b", "dup", "a2", "dup"} {
+		if !dedup.duplicate(password) {
+			kept = append(kept, password)
+		}
+	}
+	if got, want := strings.Join(kept, ","), "a,dup,once,b,a2"; got != want {
+		t.Errorf("去重结果 %s，应为 %s", got, want)
+	}
+
+	dedup.reset()
+	if dedup.duplicate("dup") {
+		t.Error("reset 后第一次出现的密码被当成重复")
+	}
+
+	// 不去重
+	none := newDedupSet(nil)
+	if none.duplicate("dup") || none.duplicate("dup") {
+		t.Error("不去重时跳过了密码")
+	}
+}
+
+func writeTestDict(t *testing.T, lines []string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "passwd.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// 字典去重后的数量和读取顺序与精确去重一致，不漏掉任何密码
+func TestPasswordDictExactDedup(t *testing.T) {
+	var lines, want []string
+	seen := make(map[string]bool)
+	for i := 0; i < 200_000; i++ {
+		password := fmt.Sprintf("pw%d", i%150_000)
+		if i%7 == 0 {
+			password = fmt.Sprintf("pw%d", i/7)
+		}
+		lines = append(lines, password)
+		if !seen[password] {
+			seen[password] = true
+			want = append(want, password)
+		}
+	}
+	dict, err := loadPasswordDict([]string{writeTestDict(t, lines)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dict.count != int64(len(want)) {
+		t.Fatalf("统计 %d 个密码，应为 %d 个", dict.count, len(want))
+	}
+
+	it := dict.iterate()
+	for round := 0; round < 2; round++ {
+		for i, w := range want {
+			got, ok := it.next()
+			if !ok || got != w {
+				t.Fatalf("第 %d 遍第 %d 个密码为 %q，应为 %q", round+1, i, got, w)
+			}
+		}
+		if _, ok := it.next(); ok {
+			t.Fatalf("第 %d 遍读出的密码多于 %d 个", round+1, len(want))
+		}
+		it.rewind()
+	}
+	it.closeFile()
+}
+
+// 可能重复的密码超过上限时返回 errTooManySuspects
+func TestFindDuplicateSuspectsLimit(t *testing.T) {
+	path := writeTestDict(t, []string{"a", "b", "a", "c", "b", "c"})
+	suspects, err := findDuplicateSuspects([]string{path}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, password := range []string{"a", "b", "c"} {
+		if !suspects[password] {
+			t.Errorf("重复的密码 %s 没有被记下", password)
+		}
+	}
+	if _, err := findDuplicateSuspects([]string{path}, 2); err != errTooManySuspects {
+		t.Errorf("超过上限时返回 %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// candidateSource 候选密码来源，按顺序逐个产生候选密码，无需把全部候选密码放进内存
+type candidateSource interface {
+	// next 返回下一个候选密码，没有更多时第二个返回值为 false
+	next() (string, bool)
+	// total 候选密码总数（用于显示进度和校验续跑位置）
+	total() int64
+	// fingerprint 候选序列的指纹，指纹相同即保证按相同顺序产生相同的候选密码
+	fingerprint() string
+}
+
+// candidateSeeker 可直接跳到指定位置的候选来源（续跑时无需逐个生成前面的候选密码）
+type candidateSeeker interface {
+	seek(pos int64)
+}
+
+// candidateDictUser 使用字典的候选来源
+// 字典中曾经成功过的密码排在最前，其顺序随成功记录变化、不计入指纹，续跑时由检查点恢复
+type candidateDictUser interface {
+	dicts() []*passwordDict
+}
+
+// candidateDicts 获取候选来源使用的字典（按在候选序列中的顺序）
+func candidateDicts(source candidateSource) []*passwordDict {
+	if user, ok := source.(candidateDictUser); ok {
+		return user.dicts()
+	}
+	return nil
+}
+
+// candidateCloser 读取时打开了文件的候选来源（字典），提前结束破解时需要关闭（流式解压字典的 7z 进程随之结束）
+type candidateCloser interface {
+	close()
+}
+
+// closeCandidates 关闭候选来源打开的文件
+func closeCandidates(source candidateSource) {
+	if closer, ok := source.(candidateCloser); ok {
+		closer.close()
+	}
+}
+
+// 函数说明：跳过前 n 个候选密码
+// 参数：
+// source: 候选来源
+// n: 跳过的数量
+func skipCandidates(source candidateSource, n int64) {
+	if seeker, ok := source.(candidateSeeker); ok {
+		seeker.seek(n)
+		return
+	}
+	for i := int64(0); i < n; i++ {
+		if _, ok := source.next(); !ok {
+			return
+		}
+	}
+}
+
+// 函数说明：计算密码列表的指纹（密码及其顺序的 SHA-256）
+// 参数：
+// passwords: 密码列表
+// 返回：指纹
+func getDictFingerprint(passwords []string) string {
+	h := sha256.New()
+	for _, pass := range passwords {
+		h.Write([]byte(pass))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// listSource 按顺序逐个产生密码列表中的密码
+type listSource struct {
+	passwords []string
+	pos       int
+}
+
+func newListSource(passwords []string) *listSource {
+	return &listSource{passwords: passwords}
+}
+
+func (s *listSource) next() (string, bool) {
+	if s.pos >= len(s.passwords) {
+		return "", false
+	}
+	s.pos++
+	return s.passwords[s.pos-1], true
+}
+
+func (s *listSource) total() int64 {
+	return int64(len(s.passwords))
+}
+
+func (s *listSource) fingerprint() string {
+	return getDictFingerprint(s.passwords)
+}
+
+func (s *listSource) seek(pos int64) {
+	s.pos = int(min(pos, int64(len(s.passwords))))
+}
+
+// chainSource 依次产生多个候选来源的密码（如先试从文件名等处找到的密码，再试字典）
+type chainSource struct {
+	sources []candidateSource
+	current int
+}
+
+func newChainSource(sources ...candidateSource) *chainSource {
+	return &chainSource{sources: sources}
+}
+
+func (s *chainSource) next() (string, bool) {
+	for s.current < len(s.sources) {
+		if pass, ok := s.sources[s.current].next(); ok {
+			return pass, true
+		}
+		s.current++
+	}
+	return "", false
+}
+
+func (s *chainSource) total() int64 {
+	var total int64
+	for _, source := range s.sources {
+		total += source.total()
+	}
+	return total
+}
+
+func (s *chainSource) fingerprint() string {
+	h := sha256.New()
+	for _, source := range s.sources {
+		h.Write([]byte(source.fingerprint()))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *chainSource) dicts() []*passwordDict {
+	var dicts []*passwordDict
+	for _, source := range s.sources {
+		dicts = append(dicts, candidateDicts(source)...)
+	}
+	return dicts
+}
+
+func (s *chainSource) close() {
+	for _, source := range s.sources {
+		closeCandidates(source)
+	}
+}
+
+func (s *chainSource) seek(pos int64) {
+	for s.current = 0; s.current < len(s.sources); s.current++ {
+		source := s.sources[s.current]
+		if pos < source.total() {
+			skipCandidates(source, pos)
+			return
+		}
+		pos -= source.total()
+	}
+}
+
+// dictSource 从字典流式读取密码，有规则时逐条应用规则，边生成边测试
+// 先按原样测试全部密码，再依次对全部密码应用第 1 条、第 2 条……规则：
+// 原密码命中的可能性最大，越靠前的规则通常越常用
+type dictSource struct {
+	dict  *passwordDict
+	rules []passwordRule
+	round int // 0 表示原样，i 表示第 i 条规则
+	it    *dictIterator
+}
+
+func newDictSource(dict *passwordDict, rules []passwordRule) *dictSource {
+	return &dictSource{dict: dict, rules: rules, it: dict.iterate()}
+}
+
+func (s *dictSource) next() (string, bool) {
+	word, ok := s.it.next()
+	for !ok {
+		// 本轮读完，从头读字典应用下一条规则
+		if s.round >= len(s.rules) || s.dict.count == 0 {
+			return "", false
+		}
+		s.round++
+		s.it.rewind()
+		word, ok = s.it.next()
+	}
+	if s.round == 0 {
+		return word, true
+	}
+	return s.rules[s.round-1].apply(word), true
+}
+
+func (s *dictSource) dicts() []*passwordDict {
+	return []*passwordDict{s.dict}
+}
+
+func (s *dictSource) close() {
+	s.it.closeFile()
+}
+
+func (s *dictSource) total() int64 {
+	return s.dict.count * int64(len(s.rules)+1)
+}
+
+func (s *dictSource) fingerprint() string {
+	if len(s.rules) == 0 {
+		return s.dict.fingerprint
+	}
+	h := sha256.New()
+	h.Write([]byte(s.dict.fingerprint))
+	for _, rule := range s.rules {
+		h.Write([]byte{'\n'})
+		h.Write([]byte(rule.text))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *dictSource) seek(pos int64) {
+	if s.dict.count == 0 {
+		return
+	}
+	s.round = int(pos / s.dict.count)
+	s.it.rewind()
+	s.it.skip(pos % s.dict.count)
+}
+
+// 函数说明：由字典创建候选来源，存在规则文件时对每个密码应用规则
+// 参数：
+// dict: 字典
+// 返回：候选来源，使用的规则文件信息（无规则时为空）
+func newDictionarySource(dict *passwordDict) (candidateSource, string) {
+	rules, rulesInfo, err := getAllRules()
+	if err != nil {
+		// 规则文件读取失败时仍可按原样使用字典，提示后继续
+		fmt.Printf("读取规则文件失败，不使用规则: %v\n", err)
+		return newDictSource(dict, nil), ""
+	}
+	source := newDictSource(dict, rules)
+	if len(rules) > 0 {
+		rulesInfo += fmt.Sprintf("应用规则后共 %d 个候选密码", source.total())
+	}
+	return source, rulesInfo
+}
+
+// 函数说明：按当前选择的破解方式（掩码、字典+掩码、字典）创建候选来源，并显示其说明
+// 参数：
+// archivePath: 压缩文件路径（用于选择字典）
+// 返回：候选来源，找到的密码是否需要保存到 passwd.txt（不在字典中时）
+func newAttackSource(archivePath string) (candidateSource, bool) {
+	// 掩码在解析命令行或交互输入时已检查过，这里不会出错
+	if maskAttack.Mask != "" {
+		source, _ := newMaskSource(maskAttack)
+		fmt.Println(formatMaskInfo(maskAttack, source))
+		return source, true
+	}
+
+	// 获取密码（从当前目录和程序所在目录查找）
+	dict, passwordsInfo, err := getAllPasswords(archivePath)
+	if err != nil {
+		fmt.Printf("\n提示：%v\n", err)
+		fmt.Println("将尝试空密码，如果失败可以手动输入密码")
+	} else {
+		fmt.Println(passwordsInfo)
+	}
+	if hybridAttack.Mask.Mask != "" {
+		source, err := newHybridSource(dict, hybridAttack)
+		if err == nil {
+			fmt.Println(formatHybridInfo(hybridAttack, source))
+			return source, true
+		}
+		// 字典变大后组合数可能超出范围，退回普通字典
+		fmt.Printf("无法使用混合破解: %v\n", err)
+	}
+
+	source, rulesInfo := newDictionarySource(dict)
+	if rulesInfo != "" && dict.count > 0 {
+		fmt.Println(rulesInfo)
+	}
+	return source, false
+}
@@ -1,7 +1,12 @@
+//go:build windows
+
 package main
 
 import (
+	"bufio"
+	"fmt"
 	"syscall"
+	"time"
 	"unsafe"
 )
 
@@ -26,6 +31,35 @@ const (
 	CF_TEXT        = 1
 )
 
+// init 预加载 user32.dll
+func init() {
+	if err := user32.Load(); err != nil {
+		panic(fmt.Sprintf("无法加载user32.dll: %v", err))
+	}
+}
+
+// 函数说明：读取一行输入；按住 Ctrl 时改为检测右键，点右键则直接粘贴剪贴板内容
+// 参数：
+// reader: 输入读取器
+// 返回：输入或粘贴的内容
+func readInputOrPaste(reader *bufio.Reader) string {
+	state, _, _ := getAsyncKeyState.Call(uintptr(VK_CONTROL))
+	if state&0x8000 == 0 {
+		// 普通输入（使用 readLineInput 支持含空格的路径和密码）
+		return readLineInput(reader)
+	}
+	// 如果按下了 Ctrl，等待右键点击
+	time.Sleep(100 * time.Millisecond)
+	state, _, _ = getAsyncKeyState.Call(uintptr(WM_RBUTTONDOWN))
+	if state&0x8000 == 0 {
+		return ""
+	}
+	// 获取剪贴板内容
+	text := getClipboardText()
+	fmt.Println(text) // 显示粘贴的内容
+	return text
+}
+
 // 获取剪贴板内容
 func getClipboardText() string {
 	// 打开剪贴板
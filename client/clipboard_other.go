@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "bufio"
+
+// readInputOrPaste 读取一行输入（Ctrl+右键粘贴仅在 Windows 上可用，其他系统直接使用终端的粘贴）
+func readInputOrPaste(reader *bufio.Reader) string {
+	return readLineInput(reader)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// commentReadTimeout 读取压缩包注释的时间上限
+const commentReadTimeout = 10 * time.Second
+
+// reSltProperty 7z l -slt 输出中的属性行，如 "Physical Size = 123"
+var reSltProperty = regexp.MustCompile(`^[A-Z][A-Za-z ]* = `)
+
+// 函数说明：读取压缩包注释
+// ZIP 直接读取中央目录结尾的注释原始字节再按 GBK/UTF-8 解码（7z 会按系统代码页转换，可能乱码），
+// 其他格式（RAR、7z 等）和 ZIP 分卷由 7z l -slt 读取
+// 参数：
+// ctx: 上下文
+// archivePath: 压缩文件路径
+// 返回：注释，没有注释或无法读取时为空
+func readArchiveComment(ctx context.Context, archivePath string) string {
+	if getFileType(archivePath) == TYPE_ZIP {
+		if r, err := zip.OpenReader(archivePath); err == nil {
+			comment := r.Comment
+			r.Close()
+			return strings.TrimSpace(decodeGBK(comment))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commentReadTimeout)
+	defer cancel()
+	out := run7z(ctx, "l", "-slt", "-sccUTF-8", format7zPasswordArg(""), archivePath)
+	if out.Stdout == "" {
+		return ""
+	}
+	return decodeGBK(parseSltComment(out.Stdout))
+}
+
+// parseSltComment 从 7z l -slt 的输出中取出压缩包注释
+// 注释可能有多行：从 "Comment = " 开始，到下一个属性行或文件列表（「----------」）为止
+func parseSltComment(output string) string {
+	var lines []string
+	inComment := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "----------" {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, "Comment = "); ok {
+			inComment = true
+			lines = append(lines, value)
+			continue
+		}
+		if inComment {
+			if reSltProperty.MatchString(line) {
+				break
+			}
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// 注释中像密码的词：长度适中、只含可见 ASCII 字符
+const (
+	commentTokenMinLen = 3
+	commentTokenMaxLen = 32
+	commentMaxTokens   = 50
+)
+
+// 函数说明：把注释拆成词，取出像密码的词作为候选密码
+// 整条注释只有一行且像密码时，本身也作为候选（有的注释就是密码）
+// 参数：
+// comment: 压缩包注释
+// 返回：候选密码（按出现顺序，已去重）
+func extractCommentTokens(comment string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+	add := func(token string) {
+		token = strings.Trim(token, ".,;:!?'\"()[]<>{}")
+		if !seen[token] && isPasswordLikeToken(token) && len(tokens) < commentMaxTokens {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	if line := strings.TrimSpace(comment); !strings.ContainsAny(line, "\r\n") {
+		add(line)
+	}
+	// 按空白和中文标点拆分，「密码：abc」拆出 abc
+	words := strings.FieldsFunc(comment, func(r rune) bool {
+		return unicode.IsSpace(r) || r > unicode.MaxASCII
+	})
+	for _, word := range words {
+		add(word)
+	}
+	return tokens
+}
+
+// isPasswordLikeToken 判断一个词是否像密码：长度适中、只含可见 ASCII 字符，且不是纯标点
+func isPasswordLikeToken(token string) bool {
+	if len(token) < commentTokenMinLen || len(token) > commentTokenMaxLen {
+		return false
+	}
+	hasAlnum := false
+	for _, r := range token {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			hasAlnum = true
+		}
+	}
+	return hasAlnum
+}
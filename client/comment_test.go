@@ -0,0 +1,57 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractCommentTokens(t *testing.T) {
+	var many []string
+	for i := 0; i < commentMaxTokens+10; i++ {
+		many = append(many, "word"+strings.Repeat("x", i%20)+string(rune('a'+i/20)))
+	}
+	tests := []struct {
+		name    string
+		comment string
+		want    []string
+	}{
+		{"单行注释本身就是密码", "abc123", []string{"abc123"}},
+		{"含空格的单行注释只取拆出的词", "pass abc123", []string{"pass", "abc123"}},
+		{"中文和中文标点作为分隔", "解压密码：abc123，官网www.example.com", []string{"abc123", "www.example.com"}},
+		{"去掉词两端的标点", "密码 (abc123)! 另见 \"readme\".", []string{"abc123", "readme"}},
+		{"多行注释不整体作为候选", "abc123\nxyz789\nabc123", []string{"abc123", "xyz789"}},
+		{"太短、太长和纯标点的词", "ab " + strings.Repeat("x", commentTokenMaxLen+1) + " --- abc", []string{"abc"}},
+		{"空注释", "", nil},
+		{"最多取 commentMaxTokens 个", strings.Join(many, "\n"), many[:commentMaxTokens]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractCommentTokens(tt.comment); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("拆出 %q，应为 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPasswordLikeToken(t *testing.T) {
+	tests := []struct {
+		token string
+		want  bool
+	}{
+		{"abc", true},
+		{"P@ssw0rd!", true},
+		{strings.Repeat("a", commentTokenMaxLen), true},
+		{"ab", false},
+		{strings.Repeat("a", commentTokenMaxLen+1), false},
+		{"a b c", false},
+		{"密码abc", false},
+		{"!!!", false},
+		{"a\tb", false},
+	}
+	for _, tt := range tests {
+		if got := isPasswordLikeToken(tt.token); got != tt.want {
+			t.Errorf("%q: 返回 %v，应为 %v", tt.token, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+)
+
+// AppConfig 用户配置，保存在程序目录的 7zrpw.json（与 passwd.txt 同目录），命令行参数优先于配置文件
+type AppConfig struct {
+	Threads         int      `json:"threads"`          // 并发测试密码的线程数，<=0 时按 CPU 核数
+	HarvestPatterns []string `json:"harvest_patterns"` // 从文件名、注释和说明文件中提取密码的正则，为空时使用默认正则
+	ServerLookup    bool     `json:"server_lookup"`    // 破解前向服务器查询其他用户找到的密码（默认关闭）
+	LookupTimeout   int      `json:"lookup_timeout"`   // 查询服务器的时间上限（秒），<=0 时为 3 秒
+	ServerURL       string   `json:"server_url"`       // 自建服务器地址，为空时使用构建时注入的地址
+	AppKey          string   `json:"app_key"`          // 自建服务器的 app_key
+	AppSecret       string   `json:"app_secret"`       // 自建服务器的 JWT 签名密钥
+	OnConflict      string   `json:"on_conflict"`      // 解压目录中已有同名文件时的处理方式，为空时覆盖
+}
+
+// appConfig 当前生效的配置
+var appConfig AppConfig
+
+// getConfigPath 获取配置文件路径
+func getConfigPath() string {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "7zrpw.json"
+	}
+	return filepath.Join(filepath.Dir(exePath), "7zrpw.json")
+}
+
+// loadAppConfig 读取配置文件，文件不存在时使用默认配置
+func loadAppConfig() error {
+	data, err := os.ReadFile(getConfigPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &appConfig); err != nil {
+		return fmt.Errorf("解析配置文件失败: %v", err)
+	}
+	if appConfig.OnConflict != "" {
+		if err := validateConflictPolicy(appConfig.OnConflict); err != nil {
+			appConfig.OnConflict = ""
+			return fmt.Errorf("配置文件 on_conflict 错误: %v", err)
+		}
+	}
+	return nil
+}
+
+// parseOptions 解析命令行选项（覆盖配置文件中的同名项），返回剩余的非选项参数
+func parseOptions(args []string) ([]string, error) {
+	fs := flag.NewFlagSet("7zrpw", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.IntVar(&appConfig.Threads, "threads", appConfig.Threads, "并发测试密码的线程数")
+	fs.BoolVar(&appConfig.ServerLookup, "lookup", appConfig.ServerLookup, "破解前向服务器查询密码")
+	fs.StringVar(&appConfig.OnConflict, "on-conflict", appConfig.OnConflict, "解压目录中已有同名文件时的处理方式")
+	fs.StringVar(&maskAttack.Mask, "mask", "", "掩码暴力破解，如 ?d?d?d?d")
+	for i := range maskAttack.Charsets {
+		fs.StringVar(&maskAttack.Charsets[i], strconv.Itoa(i+1), "", "自定义字符集")
+	}
+	fs.BoolVar(&maskAttack.Increment, "increment", false, "掩码长度逐位递增")
+	fs.IntVar(&maskAttack.IncrementMin, "increment-min", 0, "递增的最小长度")
+	fs.IntVar(&maskAttack.IncrementMax, "increment-max", 0, "递增的最大长度")
+	var hybridRight, hybridLeft string
+	fs.StringVar(&hybridRight, "hybrid", "", "字典+掩码混合破解，掩码在右侧，如 ?d?d?d")
+	fs.StringVar(&hybridLeft, "hybrid-left", "", "掩码+字典混合破解，掩码在左侧，如 ?d?d?d?d")
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("命令行参数错误: %v", err)
+	}
+
+	if appConfig.OnConflict != "" {
+		if err := validateConflictPolicy(appConfig.OnConflict); err != nil {
+			return nil, fmt.Errorf("命令行参数错误: %v", err)
+		}
+	}
+
+	// 混合攻击的掩码共用 -1 ~ -4 与 --increment 等选项
+	if hybridRight != "" || hybridLeft != "" {
+		if maskAttack.Mask != "" || (hybridRight != "" && hybridLeft != "") {
+			return nil, fmt.Errorf("命令行参数错误: --mask、--hybrid、--hybrid-left 只能选一个")
+		}
+		hybridAttack = hybridSpec{Mask: maskAttack, Left: hybridLeft != ""}
+		hybridAttack.Mask.Mask = hybridRight + hybridLeft
+	}
+
+	// 提前检查掩码，避免处理到一半才报错
+	if maskAttack.Mask != "" {
+		if _, err := newMaskSource(maskAttack); err != nil {
+			return nil, fmt.Errorf("命令行参数错误: %v", err)
+		}
+	}
+	if hybridAttack.Mask.Mask != "" {
+		if _, err := newMaskSource(hybridAttack.Mask); err != nil {
+			return nil, fmt.Errorf("命令行参数错误: %v", err)
+		}
+	}
+	return fs.Args(), nil
+}
+
+// getCrackThreads 获取破解时并发测试密码的线程数
+func getCrackThreads() int {
+	if appConfig.Threads > 0 {
+		return appConfig.Threads
+	}
+	return runtime.NumCPU()
+}
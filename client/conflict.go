@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 解压目录中已有同名文件时的处理方式（命令行 --on-conflict 或 7zrpw.json 的 on_conflict）
+const (
+	CONFLICT_OVERWRITE       = "overwrite"         // 覆盖已有文件（默认）
+	CONFLICT_SKIP            = "skip"              // 保留已有文件，不解出同名文件
+	CONFLICT_RENAME_NEW      = "rename-new"        // 解出的同名文件改名，如 file (1).txt
+	CONFLICT_RENAME_EXISTING = "rename-existing"   // 已有的同名文件改名，如 file (1).txt
+	CONFLICT_ABORT           = "abort-if-nonempty" // 解压目录不为空时不解压
+)
+
+// CONFLICT_POLICIES 全部处理方式
+var CONFLICT_POLICIES = []string{CONFLICT_OVERWRITE, CONFLICT_SKIP, CONFLICT_RENAME_NEW, CONFLICT_RENAME_EXISTING, CONFLICT_ABORT}
+
+// conflictPreviewMax 解压前列出的同名文件数量上限
+const conflictPreviewMax = 10
+
+// stagingDirPrefix 临时解压目录的前缀（建在解压目录内，合并时只需改名）
+const stagingDirPrefix = ".7zrpw-extract-"
+
+// 函数说明：检查处理方式是否有效
+// 参数：
+// policy: 处理方式
+// 返回：错误信息
+func validateConflictPolicy(policy string) error {
+	for _, p := range CONFLICT_POLICIES {
+		if policy == p {
+			return nil
+		}
+	}
+	return fmt.Errorf("无效的同名文件处理方式 %q，可选: %s", policy, strings.Join(CONFLICT_POLICIES, "、"))
+}
+
+// getConflictPolicy 获取同名文件的处理方式，未设置时为覆盖
+func getConflictPolicy() string {
+	if appConfig.OnConflict == "" {
+		return CONFLICT_OVERWRITE
+	}
+	return appConfig.OnConflict
+}
+
+// 函数说明：获取处理方式的描述
+// 参数：
+// policy: 处理方式
+// 返回：描述
+func getConflictPolicyDesc(policy string) string {
+	switch policy {
+	case CONFLICT_SKIP:
+		return "跳过，保留已有文件"
+	case CONFLICT_RENAME_NEW:
+		return "解出的文件改名，如 file (1).txt"
+	case CONFLICT_RENAME_EXISTING:
+		return "已有文件改名，如 file (1).txt"
+	case CONFLICT_ABORT:
+		return "解压目录不为空时不解压"
+	default:
+		return "覆盖已有文件"
+	}
+}
+
+// isDirEmpty 目录是否为空（不存在或无法读取时视为空）
+func isDirEmpty(dir string) bool {
+	f, err := os.Open(dir)
+	if err != nil {
+		return true
+	}
+	defer f.Close()
+	names, _ := f.Readdirnames(1)
+	return len(names) == 0
+}
+
+// 函数说明：解压前找出解压目录中已存在的同名文件
+// 参数：
+// ctx: 上下文
+// archivePath: 压缩文件路径
+// password: 密码（文件名加密时需要）
+// extractPath: 解压路径
+// 返回：同名文件的包内路径，是否成功列出压缩包
+func findExtractConflicts(ctx context.Context, archivePath, password, extractPath string) ([]string, bool) {
+	entries, result := selectArchiveBackend(archivePath).List(ctx, archivePath, password)
+	if result != RESULT_OK {
+		return nil, false
+	}
+	var conflicts []string
+	for _, e := range entries {
+		if e.IsDir {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(extractPath, e.Path)); err == nil {
+			conflicts = append(conflicts, e.Path)
+		}
+	}
+	return conflicts, true
+}
+
+// 函数说明：显示解压前的同名文件检查结果
+// 参数：
+// conflicts: 同名文件
+// listed: 是否成功列出压缩包
+// policy: 处理方式
+func printConflictSummary(conflicts []string, listed bool, policy string) {
+	if !listed {
+		fmt.Printf("解压目录不为空，无法预先列出压缩包中的文件，同名文件将按设置处理（%s）\n", getConflictPolicyDesc(policy))
+		return
+	}
+	if len(conflicts) == 0 {
+		fmt.Println("解压目录不为空，没有同名文件")
+		return
+	}
+	fmt.Printf("解压目录中已有 %d 个同名文件，处理方式: %s\n", len(conflicts), getConflictPolicyDesc(policy))
+	for i, path := range conflicts {
+		if i >= conflictPreviewMax {
+			fmt.Printf("  ...（其余 %d 个未列出）\n", len(conflicts)-conflictPreviewMax)
+			break
+		}
+		fmt.Printf("  %s\n", path)
+	}
+}
+
+// 函数说明：生成不重名的路径：file.txt → file (1).txt、file (2).txt……
+// 参数：
+// path: 已存在的路径
+// 返回：不存在的新路径
+func uniqueName(path string) string {
+	ext := filepath.Ext(path)
+	if info, err := os.Lstat(path); err == nil && info.IsDir() {
+		ext = ""
+	}
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// conflictStats 合并时处理的同名文件数量
+type conflictStats struct {
+	skipped int
+	renamed int
+}
+
+// 函数说明：把临时目录中解出的文件合并到解压目录，同名文件按处理方式跳过或改名（同名目录合并其中的文件）
+// 参数：
+// src: 临时解压目录
+// dst: 解压目录
+// policy: 处理方式（skip、rename-new 或 rename-existing）
+// stats: 统计
+// 返回：错误信息
+func mergeExtracted(src, dst, policy string, stats *conflictStats) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		from := filepath.Join(src, e.Name())
+		to := filepath.Join(dst, e.Name())
+		info, err := os.Lstat(to)
+		if os.IsNotExist(err) {
+			if err := os.Rename(from, to); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if e.IsDir() && info.IsDir() {
+			if err := mergeExtracted(from, to, policy, stats); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch policy {
+		case CONFLICT_SKIP:
+			stats.skipped++
+		case CONFLICT_RENAME_NEW:
+			if err := os.Rename(from, uniqueName(to)); err != nil {
+				return err
+			}
+			stats.renamed++
+		case CONFLICT_RENAME_EXISTING:
+			renamed := uniqueName(to)
+			if err := os.Rename(to, renamed); err != nil {
+				return err
+			}
+			if err := os.Rename(from, to); err != nil {
+				// 改回已有文件的原名，不留下缺失的文件
+				if undoErr := os.Rename(renamed, to); undoErr != nil {
+					return fmt.Errorf("%v（已有文件已改名为 %s）", err, renamed)
+				}
+				return err
+			}
+			stats.renamed++
+		}
+	}
+	return nil
+}
+
+// 函数说明：显示合并结果
+// 参数：
+// stats: 统计
+// policy: 处理方式
+func printMergeSummary(stats conflictStats, policy string) {
+	switch {
+	case stats.skipped > 0:
+		fmt.Printf("跳过 %d 个同名文件，已有文件保持不变\n", stats.skipped)
+	case stats.renamed > 0 && policy == CONFLICT_RENAME_NEW:
+		fmt.Printf("%d 个解出的同名文件已改名（如 file (1).txt）\n", stats.renamed)
+	case stats.renamed > 0:
+		fmt.Printf("%d 个已有的同名文件已改名（如 file (1).txt）\n", stats.renamed)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTestTree 按「相对路径 → 内容」在 dir 下写入文件
+func writeTestTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readTestTree 读出 dir 下的全部文件（相对路径 → 内容）
+func readTestTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// 同名文件按处理方式跳过或改名，同名目录合并其中的文件
+func TestMergeExtracted(t *testing.T) {
+	existing := map[string]string{
+		"a.txt":        "old a",
+		"dir/b.txt":    "old b",
+		"dir/keep.txt": "keep",
+	}
+	extracted := map[string]string{
+		"a.txt":         "new a",
+		"dir/b.txt":     "new b",
+		"dir/sub/c.txt": "c",
+		"d.txt":         "d",
+	}
+	tests := []struct {
+		policy string
+		want   map[string]string
+		stats  conflictStats
+	}{
+		{CONFLICT_SKIP, map[string]string{
+			"a.txt":         "old a",
+			"dir/b.txt":     "old b",
+			"dir/keep.txt":  "keep",
+			"dir/sub/c.txt": "c",
+			"d.txt":         "d",
+		}, conflictStats{skipped: 2}},
+		{CONFLICT_RENAME_NEW, map[string]string{
+			"a.txt":         "old a",
+			"a (1).txt":     "new a",
+			"dir/b.txt":     "old b",
+			"dir/b (1).txt": "new b",
+			"dir/keep.txt":  "keep",
+			"dir/sub/c.txt": "c",
+			"d.txt":         "d",
+		}, conflictStats{renamed: 2}},
+		{CONFLICT_RENAME_EXISTING, map[string]string{
+			"a.txt":         "new a",
+			"a (1).txt":     "old a",
+			"dir/b.txt":     "new b",
+			"dir/b (1).txt": "old b",
+			"dir/keep.txt":  "keep",
+			"dir/sub/c.txt": "c",
+			"d.txt":         "d",
+		}, conflictStats{renamed: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			src, dst := t.TempDir(), t.TempDir()
+			writeTestTree(t, dst, existing)
+			writeTestTree(t, src, extracted)
+
+			var stats conflictStats
+			if err := mergeExtracted(src, dst, tt.policy, &stats); err != nil {
+				t.Fatal(err)
+			}
+			if got := readTestTree(t, dst); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("合并后为 %v，应为 %v", got, tt.want)
+			}
+			if stats != tt.stats {
+				t.Errorf("统计为 %+v，应为 %+v", stats, tt.stats)
+			}
+		})
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"file.txt":       "",
+		"file (1).txt":   "",
+		"README":         "",
+		"dir.v2/a.txt":   "",
+		"archive.tar.gz": "",
+	})
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"file.txt", "file (2).txt"},
+		{"README", "README (1)"},
+		{"dir.v2", "dir.v2 (1)"}, // 目录名中的点不当作扩展名
+		{"archive.tar.gz", "archive.tar (1).gz"},
+	}
+	for _, tt := range tests {
+		if got := uniqueName(filepath.Join(dir, tt.name)); got != filepath.Join(dir, tt.want) {
+			t.Errorf("%s 的新名字为 %s，应为 %s", tt.name, filepath.Base(got), tt.want)
+		}
+	}
+}
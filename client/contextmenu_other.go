@@ -0,0 +1,23 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errContextMenuUnsupported 右键菜单依赖 Windows 注册表
+var errContextMenuUnsupported = errors.New("右键菜单仅支持 Windows")
+
+// installContext 非 Windows 系统没有右键菜单
+func installContext() error {
+	fmt.Println(errContextMenuUnsupported)
+	return errContextMenuUnsupported
+}
+
+// uninstallContext 非 Windows 系统没有右键菜单
+func uninstallContext() error {
+	fmt.Println(errContextMenuUnsupported)
+	return errContextMenuUnsupported
+}
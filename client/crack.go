@@ -1,24 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"os/exec"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// 7z 输出中代表「密码错误 / 无法作为压缩包打开」的标记，集中维护便于增删
-var sevenZipErrorMarkers = []string{
-	"Cannot open encrypted archive",
-	"Data Error in encrypted file",
-	"ERROR:",
-	"Can't open as archive",
-	"ERROR: Wrong password",
-	"Wrong password",
-	"Archives with Errors",
-}
-
 // format7zPasswordArg 格式化 7z 的密码参数，支持含空格、引号、反斜杠等特殊字符
 func format7zPasswordArg(password string) string {
 	if password == "" {
@@ -32,119 +22,233 @@ func format7zPasswordArg(password string) string {
 	return "-p" + password
 }
 
-/*
-*
-函数说明：测试密码
-参数：
-archivePath: 压缩文件路径
-password: 密码
-返回：是否成功
-*/
-func testPassword(archivePath, password string) bool {
-	// 构建测试命令，使用7z的t命令测试文件完整性，来判断密码是否正确
-	args := []string{
-		"t",
-		format7zPasswordArg(password),
-		archivePath,
-	}
-
-	cmd := exec.Command(getSevenZipPath(), args...)
-	cmd.Env = append(os.Environ(), "LANG=C.UTF-8")
-
-	resultChan := make(chan bool, 1)
-
-	go func() {
-		output, _ := cmd.CombinedOutput()
-		outputStr := string(output)
-
-		// 如果输出包含以下信息，说明密码正确
-		if strings.Contains(outputStr, "Everything is Ok") {
-			resultChan <- true
-			return
-		}
-
-		// 命中任一错误标记，说明密码错误
-		for _, marker := range sevenZipErrorMarkers {
-			if strings.Contains(outputStr, marker) {
-				resultChan <- false
-				return
-			}
-		}
-
-		resultChan <- true
-	}()
-
-	// 等待结果或超时
-	select {
-	case result := <-resultChan: // 获取结果
-		return result // 返回结果
-	case <-time.After(2 * time.Second):
-		// 边界：2秒内没有错误标记，说明是正确密码
-		// 确保检查大文件时，7z.exe会一直检查整个文件直至检查结束。
-		// 当大于2秒时，基本可以认为密码正确
-		if cmd.Process != nil {
-			cmd.Process.Kill() // 强制终止命令
-		}
-		return true // 返回true
-	}
-}
-
 // 函数说明：格式化进度显示
 // 参数：
-// current: 当前尝试的密码数量
+// current: 已尝试的密码数量
 // total: 总的密码数量
 // currentPass: 当前尝试的密码
+// speed: 测试速度（密码/秒）
 // 返回：格式化后的进度显示字符串
-func formatProgress(current, total int, currentPass string) string {
-	percent := float64(current) * 100.0 / float64(total)
+func formatProgress(current, total int64, currentPass string, speed float64) string {
+	percent := 100.0
+	if total > 0 {
+		percent = float64(current) * 100.0 / float64(total)
+	}
 
 	// 使用已有的 decodeGBK 函数处理密码字符串
 	cleanPass := decodeGBK(currentPass)
 
 	// 先清除整行，再显示新内容
-	return fmt.Sprintf("\r%s\r正在尝试密码... %d/%d （%.1f%%） [%s]",
+	return fmt.Sprintf("\r%s\r正在尝试密码... %d/%d （%.1f%%） %.1f 密码/秒 [%s]",
 		strings.Repeat(" ", 100), // 清除整行
 		current,
 		total,
 		percent,
+		speed,
 		cleanPass)
 }
 
 // 函数说明：破解压缩文件
+// 多个线程并发测试密码，任一线程找到正确密码后取消其余线程并终止其 7z 进程
 // 参数：
+// parent: 上下文，取消（Ctrl+C）时终止全部 7z 进程并保存进度，下次可以续上
 // archivePath: 压缩文件路径
-// passwords: 密码列表
-// 返回：密码，错误信息
-func crackArchive(archivePath string, passwords []string) (string, error) {
+// source: 候选密码来源（结束时关闭其打开的字典文件）
+// session: 破解进度检查点，从 session.Tested 处开始并定时保存进度
+// 返回：密码，错误信息（被取消时为 context.Canceled）
+func crackArchive(parent context.Context, archivePath string, source candidateSource, session *crackSession) (string, error) {
 	startTime := time.Now() // 记录开始时间
+	defer closeCandidates(source)
+
+	// 探测压缩包，确定之后如何验证每个密码
+	verifier := newPasswordVerifier(archivePath)
 
 	// 首先尝试空密码
-	if testPassword(archivePath, "") {
+	result := verifier.testPassword(parent, "")
+	if parent.Err() != nil {
+		return "", parent.Err()
+	}
+	switch result {
+	case RESULT_OK:
+		session.remove()
 		elapsed := time.Since(startTime)
 		fmt.Printf("\n破解用时: %s\n", formatDuration(elapsed))
 		return "", nil
+	case RESULT_CORRUPT_DATA, RESULT_MISSING_VOLUME, RESULT_UNSUPPORTED:
+		// 与密码无关的问题，继续尝试密码没有意义
+		session.remove()
+		return "", &archiveError{Result: result}
+	case RESULT_FAILED:
+		// 7z 无法运行等问题，每个密码都会同样失败；保留已保存的进度，问题解决后可以继续
+		return "", &archiveError{Result: result}
 	}
 
-	// 记录已尝试的密码数量
-	testedCount := 0
+	// 从上次的进度继续
+	start := session.Tested
+	if start > 0 {
+		fmt.Printf("从第 %d 个密码继续...\n", start+1)
+	}
+	checkpoint := newCrackCheckpoint(start)
 
-	// 逐个尝试密码
-	for i, pass := range passwords {
-		testedCount++
-		// 显示进度条
-		fmt.Print(formatProgress(i+1, len(passwords), pass))
+	threads := getCrackThreads()
+	if remaining := source.total() - start; int64(threads) > remaining {
+		threads = int(remaining)
+	}
 
-		// 测试密码
-		if testPassword(archivePath, pass) {
-			elapsed := time.Since(startTime)
-			speed := float64(testedCount) / elapsed.Seconds()
-			fmt.Printf("\n破解用时: %s (平均 %.1f 密码/秒)\n", formatDuration(elapsed), speed)
-			return pass, nil
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	// 候选密码及其在候选序列中的位置（用于记录进度）
+	type crackJob struct {
+		index    int64
+		password string
+	}
+	jobs := make(chan crackJob)
+	found := make(chan string, 1)
+	var testedCount atomic.Int64 // 已完成测试的密码数量（所有线程合计）
+	var lastPass atomic.Value    // 最近开始测试的密码，仅用于进度显示
+	lastPass.Store("")
+	var unknownMu sync.Mutex
+	var unknownPasswords []string // 超过安全上限仍未能确认的密码
+	var archiveErr atomic.Value   // 测试中发现的与密码无关的压缩包问题
+
+	// 启动工作线程
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				pass := job.password
+				lastPass.Store(pass)
+				result := verifier.testPassword(ctx, pass)
+				if ctx.Err() != nil {
+					// 其他线程已找到密码，本次测试被中断，不计数
+					return
+				}
+				if result == RESULT_FAILED {
+					// 7z 无法运行等问题，停止破解；该密码未测试，不计入进度
+					archiveErr.CompareAndSwap(nil, &archiveError{Result: result})
+					cancel()
+					return
+				}
+				testedCount.Add(1)
+				checkpoint.complete(job.index)
+				switch result {
+				case RESULT_OK:
+					select {
+					case found <- pass:
+						cancel() // 终止其余线程的 7z 进程
+					default:
+					}
+					return
+				case RESULT_WRONG_PASSWORD:
+				case RESULT_CORRUPT_DATA, RESULT_MISSING_VOLUME, RESULT_UNSUPPORTED:
+					// 与密码无关的问题，停止破解
+					archiveErr.CompareAndSwap(nil, &archiveError{Result: result})
+					cancel()
+					return
+				default:
+					// 超时，无法确认该密码
+					unknownMu.Lock()
+					unknownPasswords = append(unknownPasswords, pass)
+					unknownMu.Unlock()
+				}
+			}
+		}()
+	}
+
+	// 分发密码，找到后停止分发（结束前不能关闭候选来源）
+	dispatched := make(chan struct{})
+	defer func() {
+		cancel()
+		<-dispatched
+	}()
+	go func() {
+		defer close(dispatched)
+		defer close(jobs)
+		skipCandidates(source, start)
+		for i := start; ; i++ {
+			pass, ok := source.next()
+			if !ok {
+				return
+			}
+			select {
+			case jobs <- crackJob{index: i, password: pass}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// 等待所有线程结束，期间定时刷新进度
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	showProgress := func() {
+		speed := float64(testedCount.Load()) / time.Since(startTime).Seconds()
+		fmt.Print(formatProgress(start+testedCount.Load(), source.total(), lastPass.Load().(string), speed))
+	}
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	lastSave := time.Now()
+	for waiting := true; waiting; {
+		select {
+		case <-done:
+			waiting = false
+		case <-ticker.C:
+			showProgress()
+			// 定时保存进度，程序中途被关闭时下次可以续上
+			if time.Since(lastSave) >= sessionSaveInterval {
+				session.Tested = checkpoint.position()
+				session.save()
+				lastSave = time.Now()
+			}
 		}
 	}
+	showProgress()
 
 	elapsed := time.Since(startTime)
-	speed := float64(testedCount) / elapsed.Seconds()
-	fmt.Printf("\n破解用时: %s (平均 %.1f 密码/秒)\n", formatDuration(elapsed), speed)
+	speed := float64(testedCount.Load()) / elapsed.Seconds()
+	fmt.Printf("\n破解用时: %s (平均 %.1f 密码/秒，%d 线程)\n", formatDuration(elapsed), speed, threads)
+
+	select {
+	case pass := <-found:
+		session.remove()
+		return pass, nil
+	default:
+	}
+
+	// 被取消（Ctrl+C）：保存进度，下次处理该文件时从这里继续
+	if parent.Err() != nil {
+		session.Tested = checkpoint.position()
+		session.save()
+		fmt.Printf("已取消破解: 已测试 %d/%d 个密码，进度已保存，下次处理该文件时可继续\n", session.Tested, source.total())
+		return "", parent.Err()
+	}
+
+	// 7z 无法运行：保存进度，问题解决后从这里继续
+	if err, ok := archiveErr.Load().(*archiveError); ok && err.Result == RESULT_FAILED {
+		session.Tested = checkpoint.position()
+		session.save()
+		return "", err
+	}
+
+	// 破解已结束（压缩包有问题或全部测试完），不再需要续
+	session.remove()
+	if err, ok := archiveErr.Load().(*archiveError); ok {
+		return "", err
+	}
+
+	// 边界：未找到确定正确的密码，列出未能确认的密码供手动重试
+	if len(unknownPasswords) > 0 {
+		fmt.Printf("以下 %d 个密码测试超时，未能确认，可手动输入重试:\n", len(unknownPasswords))
+		for _, pass := range unknownPasswords {
+			fmt.Printf("  [%s]\n", decodeGBK(pass))
+		}
+	}
 	return "", fmt.Errorf("未找到正确密码")
 }
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// 7z 无法运行（如内存不足退出）时立即停止破解，而不是把整个字典都跑一遍
+func TestCrackArchiveStopsOnFailed(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("用 shell 脚本模拟 7z")
+	}
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	// 模拟的 7z：空密码报告密码错误，其他密码以退出码 8（内存不足）退出
+	calls := filepath.Join(dir, "calls")
+	script := fmt.Sprintf(`#!/bin/sh
+echo >> %q
+for arg in "$@"; do
+	if [ "$arg" = "-p" ]; then
+		echo "ERROR: Wrong password" >&2
+		exit 2
+	fi
+done
+exit 8
+`, calls)
+	fake7z := filepath.Join(dir, "7z")
+	if err := os.WriteFile(fake7z, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	saved := *sevenZip
+	sevenZip.path = fake7z
+	defer func() { *sevenZip = saved }()
+	savedConfig := appConfig
+	appConfig.Threads = 2
+	defer func() { appConfig = savedConfig }()
+
+	archivePath := filepath.Join(dir, "test.bin")
+	if err := os.WriteFile(archivePath, []byte("not an archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var passwords []string
+	for i := 0; i < 100; i++ {
+		passwords = append(passwords, fmt.Sprintf("p%d", i))
+	}
+	dict, err := loadPasswordDict([]string{writeTestDict(t, passwords)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := newDictSource(dict, nil)
+	_, err = crackArchive(context.Background(), archivePath, source, loadCrackSession(archivePath, source))
+
+	var archiveErr *archiveError
+	if !errors.As(err, &archiveErr) || archiveErr.Result != RESULT_FAILED {
+		t.Fatalf("返回 %v，应为 7z 执行失败", err)
+	}
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 列出文件、测试空密码，加上每个线程各一次失败的测试
+	if n := strings.Count(string(data), "\n"); n > 2+appConfig.Threads {
+		t.Errorf("7z 被调用了 %d 次", n)
+	}
+}
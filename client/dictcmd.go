@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// dictStatsMaxLen 长度分布中单独统计的最大长度，更长的密码合并为一项
+const dictStatsMaxLen = 20
+
+// DICT_USAGE 字典管理子命令的用法
+const DICT_USAGE = `用法: 7zrpw dict <命令> [--file 字典路径] [参数]
+
+  add 密码...           添加密码（已存在的不重复添加）
+  remove 密码...        删除密码
+  dedupe                去除重复密码，保留第一次出现的位置
+  merge a.txt b.txt...  把其他字典中没有的密码追加到字典末尾
+  stats                 统计密码数量、重复、长度分布和编码问题
+  sort --by-hits        曾经成功过的密码移到最前面（按成功次数排序）
+
+默认操作新密码的保存位置（程序目录的 passwd.vault 或 passwd.txt）。
+修改时先写临时文件再替换，中途退出不会损坏原字典。`
+
+// 函数说明：执行字典管理子命令（7zrpw dict ...）
+// 参数：
+// args: dict 之后的命令行参数
+// 返回：错误信息
+func runDictCommand(args []string) error {
+	if len(args) == 0 {
+		fmt.Println(DICT_USAGE)
+		return nil
+	}
+	command := args[0]
+	target := getPasswordSavePath()
+	var rest []string
+	byHits := false
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--file":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--file 需要指定字典路径")
+			}
+			i++
+			target = args[i]
+		case "--by-hits":
+			byHits = true
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+
+	switch command {
+	case "add":
+		if len(rest) == 0 {
+			return fmt.Errorf("请指定要添加的密码")
+		}
+		return dictAdd(target, rest)
+	case "remove":
+		if len(rest) == 0 {
+			return fmt.Errorf("请指定要删除的密码")
+		}
+		return dictRemove(target, rest)
+	case "dedupe":
+		return dictDedupe(target)
+	case "merge":
+		if len(rest) == 0 {
+			return fmt.Errorf("请指定要合并的字典")
+		}
+		return dictMerge(target, rest)
+	case "stats":
+		return dictStats(target)
+	case "sort":
+		if !byHits {
+			return fmt.Errorf("目前只支持 dict sort --by-hits")
+		}
+		return dictSortByHits(target)
+	}
+	return fmt.Errorf("未知的字典命令: %s\n\n%s", command, DICT_USAGE)
+}
+
+// forEachPassword 流式读取密码文件中的每个密码（去除首尾空白，跳过空行）
+func forEachPassword(path string, fn func(password string)) error {
+	return scanPasswordFile(path, func(line string) {
+		if password := strings.TrimSpace(line); password != "" {
+			fn(password)
+		}
+	})
+}
+
+// forEachDictLine 流式读取字典中的每一行，原样保留首尾空白（只去掉换行符，跳过空行），重写字典时不改动已有的密码
+func forEachDictLine(path string, fn func(line string)) error {
+	return scanPasswordFile(path, func(line string) {
+		if line != "" {
+			fn(line)
+		}
+	})
+}
+
+// scanPasswordFile 流式读取密码文件中的每一行（不含换行符）
+func scanPasswordFile(path string, fn func(line string)) error {
+	file, err := openPasswordFile(path)
+	if err != nil {
+		return fmt.Errorf("打开密码文件失败: %v", err)
+	}
+	scanner := newPasswordScanner(file)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+	err = scanner.Err()
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("读取密码文件 %s 失败: %v", path, err)
+	}
+	return nil
+}
+
+// dictRewriter 把字典重写到同目录的临时文件，全部写完后再替换原文件
+type dictRewriter struct {
+	path    string
+	file    *os.File
+	w       *bufio.Writer
+	vault   *vaultWriter
+	written int64
+}
+
+// 函数说明：开始重写字典（支持明文字典和 .vault 密码库，密码库沿用原主密码）
+// 参数：
+// path: 字典路径
+// 返回：重写器，错误信息
+func newDictRewriter(path string) (*dictRewriter, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".gz", ".zst", ".7z":
+		return nil, fmt.Errorf("不支持修改压缩字典 %s，请先解压为明文字典", path)
+	}
+
+	var vault *passwordVault
+	if ext == ".vault" {
+		var err error
+		if vault, err = getUnlockedVault(path); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	// 临时文件创建时为 0600，替换后沿用原字典的权限
+	if info, err := os.Stat(path); err == nil {
+		if err := file.Chmod(info.Mode().Perm()); err != nil {
+			file.Close()
+			os.Remove(file.Name())
+			return nil, fmt.Errorf("设置临时文件权限失败: %v", err)
+		}
+	}
+	r := &dictRewriter{path: path, file: file}
+	if vault != nil {
+		r.vault = newVaultWriter(file, vault)
+	} else {
+		r.w = bufio.NewWriterSize(file, 64*1024)
+	}
+	return r, nil
+}
+
+// writePassword 写入一个密码
+func (r *dictRewriter) writePassword(password string) {
+	if r.vault != nil {
+		r.vault.writePassword(password)
+	} else {
+		r.w.WriteString(password + "\n")
+	}
+	r.written++
+}
+
+// commit 写完并落盘后替换原文件
+func (r *dictRewriter) commit() error {
+	var err error
+	if r.vault != nil {
+		err = r.vault.finish()
+	} else {
+		err = r.w.Flush()
+	}
+	if err == nil {
+		err = r.file.Sync()
+	}
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(r.file.Name())
+		return fmt.Errorf("写入临时文件失败: %v", err)
+	}
+	if err := os.Rename(r.file.Name(), r.path); err != nil {
+		os.Remove(r.file.Name())
+		return fmt.Errorf("替换 %s 失败: %v", r.path, err)
+	}
+	return nil
+}
+
+// abort 放弃重写，原文件保持不变
+func (r *dictRewriter) abort() {
+	r.file.Close()
+	os.Remove(r.file.Name())
+}
+
+// rewriteDict 读取原字典，经 filter 决定每一行是否保留（保留的行原样写回），全部成功后替换原字典
+func rewriteDict(path string, filter func(line string) bool) (*dictRewriter, error) {
+	rewriter, err := newDictRewriter(path)
+	if err != nil {
+		return nil, err
+	}
+	err = forEachDictLine(path, func(line string) {
+		if filter(line) {
+			rewriter.writePassword(line)
+		}
+	})
+	if err != nil {
+		rewriter.abort()
+		return nil, err
+	}
+	return rewriter, nil
+}
+
+// dictAdd 逐个追加密码
+func dictAdd(path string, passwords []string) error {
+	for _, password := range passwords {
+		password = strings.TrimSpace(password)
+		if password == "" {
+			continue
+		}
+		added, err := appendPasswordToFile(path, password)
+		if err != nil {
+			return err
+		}
+		if added {
+			fmt.Printf("已添加: %s\n", password)
+		} else {
+			fmt.Printf("已存在: %s\n", password)
+		}
+	}
+	return nil
+}
+
+// dictRemove 删除指定的密码（所有出现位置，包括首尾带空白、使用时等同于该密码的行）
+func dictRemove(path string, passwords []string) error {
+	if !fileExists(path) {
+		return fmt.Errorf("%s 不存在", path)
+	}
+	remove := make(map[string]bool, len(passwords))
+	for _, password := range passwords {
+		remove[strings.TrimSpace(password)] = true
+	}
+	var removed int64
+	rewriter, err := rewriteDict(path, func(line string) bool {
+		if remove[strings.TrimSpace(line)] {
+			removed++
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		rewriter.abort()
+		fmt.Println("字典中没有要删除的密码")
+		return nil
+	}
+	if err := rewriter.commit(); err != nil {
+		return err
+	}
+	fmt.Printf("已从 %s 删除 %d 行\n", path, removed)
+	return nil
+}
+
+// dictDedupe 去除重复的行，保留每一行第一次出现的位置
+func dictDedupe(path string) error {
+	if !fileExists(path) {
+		return fmt.Errorf("%s 不存在", path)
+	}
+	suspects, err := findDuplicateLines([]string{path})
+	if err != nil {
+		return err
+	}
+	dedup := newDedupSet(suspects)
+	var removed int64
+	rewriter, err := rewriteDict(path, func(line string) bool {
+		if dedup.duplicate(line) {
+			removed++
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		rewriter.abort()
+		fmt.Println("字典中没有重复的密码")
+		return nil
+	}
+	if err := rewriter.commit(); err != nil {
+		return err
+	}
+	fmt.Printf("已去除 %d 个重复密码，剩余 %d 个\n", removed, rewriter.written)
+	return nil
+}
+
+// dictMerge 把其他字典中的新密码按顺序追加到字典末尾，字典原有内容不变
+func dictMerge(path string, sources []string) error {
+	for _, source := range sources {
+		if !fileExists(source) {
+			return fmt.Errorf("%s 不存在", source)
+		}
+	}
+	paths := sources
+	if fileExists(path) {
+		paths = append([]string{path}, sources...)
+	} else if strings.EqualFold(filepath.Ext(path), ".vault") {
+		return fmt.Errorf("%s 不存在，请先用 --vault-create 新建密码库", path)
+	} else if err := os.WriteFile(path, nil, 0644); err != nil {
+		return fmt.Errorf("创建 %s 失败: %v", path, err)
+	}
+	suspects, err := findDuplicateLines(paths)
+	if err != nil {
+		return err
+	}
+
+	dedup := newDedupSet(suspects)
+	rewriter, err := rewriteDict(path, func(line string) bool {
+		dedup.duplicate(line)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	var added int64
+	for _, source := range sources {
+		err := forEachDictLine(source, func(line string) {
+			if dedup.duplicate(line) {
+				return
+			}
+			rewriter.writePassword(line)
+			added++
+		})
+		if err != nil {
+			rewriter.abort()
+			return err
+		}
+	}
+	if added == 0 {
+		rewriter.abort()
+		fmt.Println("没有新的密码")
+		return nil
+	}
+	if err := rewriter.commit(); err != nil {
+		return err
+	}
+	fmt.Printf("已合并 %d 个新密码到 %s\n", added, path)
+	return nil
+}
+
+// dictSortByHits 把曾经成功过的密码按成功次数移到最前面，其余密码保持原有顺序
+func dictSortByHits(path string) error {
+	if !fileExists(path) {
+		return fmt.Errorf("%s 不存在", path)
+	}
+	hits := loadPasswordHits()
+	hotSet := make(map[string]bool)
+	var hot []string
+	err := forEachDictLine(path, func(line string) {
+		if hits[line].Count > 0 && !hotSet[line] {
+			hotSet[line] = true
+			hot = append(hot, line)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(hot) == 0 {
+		fmt.Println("字典中没有曾经成功过的密码")
+		return nil
+	}
+	sortPasswordsByHits(hot, hits)
+
+	rewriter, err := newDictRewriter(path)
+	if err != nil {
+		return err
+	}
+	for _, password := range hot {
+		rewriter.writePassword(password)
+	}
+	err = forEachDictLine(path, func(line string) {
+		if !hotSet[line] {
+			rewriter.writePassword(line)
+		}
+	})
+	if err != nil {
+		rewriter.abort()
+		return err
+	}
+	if err := rewriter.commit(); err != nil {
+		return err
+	}
+	fmt.Printf("已把 %d 个曾经成功过的密码移到最前面\n", len(hot))
+	return nil
+}
+
+// dictStats 统计字典：密码数量、重复、长度分布和编码问题
+func dictStats(path string) error {
+	if !fileExists(path) {
+		return fmt.Errorf("%s 不存在", path)
+	}
+	suspects, err := findDuplicateSuspects([]string{path}, 0)
+	if err != nil {
+		return err
+	}
+
+	file, err := openPasswordFile(path)
+	if err != nil {
+		return fmt.Errorf("打开密码文件失败: %v", err)
+	}
+	defer file.Close()
+
+	var total, lines, emptyLines, duplicates, invalidUTF8, controlChars, padded int64
+	var lengths [dictStatsMaxLen + 2]int64
+	dedup := newDedupSet(suspects)
+	scanner := newPasswordScanner(file)
+	for scanner.Scan() {
+		lines++
+		line := scanner.Text()
+		password := strings.TrimSpace(line)
+		if password == "" {
+			emptyLines++
+			continue
+		}
+		total++
+		if password != strings.TrimRight(line, "\r") {
+			padded++
+		}
+		if dedup.duplicate(password) {
+			duplicates++
+		}
+		if !utf8.ValidString(password) {
+			invalidUTF8++
+		} else if strings.IndexFunc(password, unicode.IsControl) >= 0 {
+			controlChars++
+		}
+		lengths[min(utf8.RuneCountInString(password), dictStatsMaxLen+1)]++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取密码文件 %s 失败: %v", path, err)
+	}
+
+	fmt.Printf("字典: %s\n", path)
+	fmt.Printf("密码: %d 个（不重复 %d 个，重复 %d 个）\n", total, total-duplicates, duplicates)
+	fmt.Printf("空行: %d 行\n", emptyLines)
+	fmt.Println("\n长度分布:")
+	printLengthHistogram(os.Stdout, lengths[:], total)
+	fmt.Println("\n编码问题:")
+	fmt.Printf("  非 UTF-8（可能是 GBK 编码）: %d 个\n", invalidUTF8)
+	fmt.Printf("  含控制字符: %d 个\n", controlChars)
+	fmt.Printf("  首尾有空格（使用时会去掉）: %d 个\n", padded)
+	return nil
+}
+
+// printLengthHistogram 按长度输出密码数量和比例条
+func printLengthHistogram(w io.Writer, lengths []int64, total int64) {
+	if total == 0 {
+		return
+	}
+	for length, count := range lengths {
+		if count == 0 {
+			continue
+		}
+		label := fmt.Sprintf("%2d", length)
+		if length > dictStatsMaxLen {
+			label = fmt.Sprintf(">%d", dictStatsMaxLen)
+		}
+		fmt.Fprintf(w, "  %3s: %10d  %5.1f%% %s\n", label, count,
+			float64(count)*100/float64(total), strings.Repeat("█", int(count*40/total)))
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDictCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		dict    *string           // 原字典内容，nil 表示不存在
+		sources map[string]string // 同目录下的其他字典
+		run     func(dir, path string) error
+		want    string
+	}{
+		{
+			name: "add",
+			dict: stringPtr("a\n"),
+			run:  func(dir, path string) error { return dictAdd(path, []string{"b", "a", " c "}) },
+			want: "a\nb\nc\n",
+		},
+		{
+			name: "remove 删除所有出现位置",
+			dict: stringPtr("a\nb\n a \nc\nb\n"),
+			run:  func(dir, path string) error { return dictRemove(path, []string{"a", "b"}) },
+			want: "c\n",
+		},
+		{
+			name: "dedupe 保留第一次出现的位置和首尾空白",
+			dict: stringPtr("b\na\nb\n a\nc\na\n\n c \n"),
+			run:  func(dir, path string) error { return dictDedupe(path) },
+			want: "b\na\n a\nc\n c \n",
+		},
+		{
+			name: "dedupe CRLF 字典",
+			dict: stringPtr("a\r\nb\r\na\r\n"),
+			run:  func(dir, path string) error { return dictDedupe(path) },
+			want: "a\nb\n",
+		},
+		{
+			name:    "merge 到已有字典",
+			dict:    stringPtr("a\nx\n"),
+			sources: map[string]string{"s1.txt": "x\ny\n", "s2.txt": "y\n z\n"},
+			run: func(dir, path string) error {
+				return dictMerge(path, []string{filepath.Join(dir, "s1.txt"), filepath.Join(dir, "s2.txt")})
+			},
+			want: "a\nx\ny\n z\n",
+		},
+		{
+			name:    "merge 到不存在的字典",
+			sources: map[string]string{"s1.txt": "x\ny\n", "s2.txt": "y\nz\n"},
+			run: func(dir, path string) error {
+				return dictMerge(path, []string{filepath.Join(dir, "s1.txt"), filepath.Join(dir, "s2.txt")})
+			},
+			want: "x\ny\nz\n",
+		},
+		{
+			name: "sort --by-hits",
+			dict: stringPtr("a\nb\nc\nd\nc\n"),
+			run: func(dir, path string) error {
+				now := time.Now()
+				writeTestHits(t, map[string]passwordHit{
+					"b": {Count: 1, LastSuccess: now},
+					"c": {Count: 2, LastSuccess: now},
+				})
+				return dictSortByHits(path)
+			},
+			want: "c\nb\na\nd\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "passwd.txt")
+			if tt.dict != nil {
+				writeTestTree(t, dir, map[string]string{"passwd.txt": *tt.dict})
+			}
+			writeTestTree(t, dir, tt.sources)
+
+			if err := tt.run(dir, path); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("字典内容为 %q，应为 %q", data, tt.want)
+			}
+			assertNoTempFiles(t, dir)
+		})
+	}
+}
+
+// 重写后沿用原字典的权限
+func TestDictRewriteKeepsMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows 没有 Unix 权限位")
+	}
+	path := filepath.Join(t.TempDir(), "passwd.txt")
+	if err := os.WriteFile(path, []byte("a\na\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := dictDedupe(path); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0640 {
+		t.Errorf("权限为 %o，应为 640", mode)
+	}
+}
+
+// 合并失败时原字典不变，也不留下临时文件
+func TestDictMergeFailureKeepsOriginal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "passwd.txt")
+	writeTestTree(t, dir, map[string]string{
+		"passwd.txt": "a\nb\n",
+		// 超过读取缓冲区的行使读取出错
+		"bad.txt": "c\n" + strings.Repeat("x", passwordScanBufferSize+1) + "\n",
+	})
+	if err := dictMerge(path, []string{filepath.Join(dir, "bad.txt")}); err == nil {
+		t.Fatal("读取出错时应返回错误")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a\nb\n" {
+		t.Errorf("原字典被改为 %q", data)
+	}
+	assertNoTempFiles(t, dir)
+}
+
+// assertNoTempFiles 检查重写字典后没有留下临时文件
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	tmp, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tmp) > 0 {
+		t.Errorf("留下了临时文件: %q", tmp)
+	}
+}
+
+// stringPtr 返回字符串的指针
+func stringPtr(s string) *string {
+	return &s
+}
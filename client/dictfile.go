@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// PASSWD_FILE_NAMES 各目录中按顺序查找的密码文件：明文、gzip、zstd、带主密码的 7z 和加密密码库
+var PASSWD_FILE_NAMES = []string{"passwd.txt", "passwd.txt.gz", "passwd.txt.zst", "passwd.7z", VAULT_FILE_NAME}
+
+// compressedDictRatio 估算压缩字典解压后大小的倍数（用于决定去重的布隆过滤器大小）
+const compressedDictRatio = 4
+
+// dictPassphraseAttempts 主密码最多输入次数
+const dictPassphraseAttempts = 3
+
+// 已解锁的 7z 字典主密码与密码库，每次运行只需输入一次
+// dictSecretsMu 只保护这两个表；dictPromptMu 使同一时间只有一个主密码提示，
+// 等待输入时不占用 dictSecretsMu，其他线程照常读取已解锁的字典和密码库
+var (
+	dictSecretsMu  sync.Mutex
+	dictPromptMu   sync.Mutex
+	dict7zSecrets  = make(map[string]string)
+	unlockedVaults = make(map[string]*passwordVault)
+)
+
+// lookupDict7zSecret 查找已输入的 7z 字典主密码
+func lookupDict7zSecret(path string) (string, bool) {
+	dictSecretsMu.Lock()
+	defer dictSecretsMu.Unlock()
+	password, ok := dict7zSecrets[path]
+	return password, ok
+}
+
+// lookupUnlockedVault 查找已解锁的密码库
+func lookupUnlockedVault(path string) (*passwordVault, bool) {
+	dictSecretsMu.Lock()
+	defer dictSecretsMu.Unlock()
+	vault, ok := unlockedVaults[path]
+	return vault, ok
+}
+
+// 函数说明：按扩展名打开密码文件，返回解压/解密后的明文（明文不写入磁盘）
+// .gz 直接解压，.zst 与 .7z 交给内置 7z 解到标准输出，.vault 为加密密码库
+// 参数：
+// path: 密码文件路径
+// 返回：明文读取器，错误信息
+func openPasswordFile(path string) (io.ReadCloser, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz":
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%s 不是有效的 gzip 文件: %v", path, err)
+		}
+		return &stackedReadCloser{Reader: gz, closers: []io.Closer{gz, file}}, nil
+
+	case ".zst":
+		stream, err := open7zStream("e", "-so", "-tzstd", path)
+		if err != nil {
+			return nil, err
+		}
+		return &zstdDictStream{ReadCloser: stream}, nil
+
+	case ".7z":
+		password, err := getDict7zPassword(path)
+		if err != nil {
+			return nil, err
+		}
+		return open7zStream("e", "-so", format7zPasswordArg(password), path)
+
+	case ".vault":
+		vault, err := getUnlockedVault(path)
+		if err != nil {
+			return nil, err
+		}
+		return vault.open()
+	}
+	return os.Open(path)
+}
+
+// estimatePasswordDataSize 估算密码文件明文的大小
+func estimatePasswordDataSize(path string, size int64) int64 {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz", ".zst", ".7z":
+		return size * compressedDictRatio
+	}
+	return size
+}
+
+// stackedReadCloser 关闭时依次关闭多层读取器
+type stackedReadCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (s *stackedReadCloser) Close() error {
+	var firstErr error
+	for _, c := range s.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// sevenZipStream 7z 解到标准输出的数据流
+type sevenZipStream struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr bytes.Buffer
+	eof    bool
+}
+
+// open7zStream 启动 7z 并读取其标准输出（7z 的 -so）
+func open7zStream(args ...string) (io.ReadCloser, error) {
+	fullArgs := append([]string{args[0], "-bse2", "-bsp0"}, args[1:]...)
+	s := &sevenZipStream{cmd: exec.Command(getSevenZipPath(), fullArgs...)}
+	s.cmd.Env = append(os.Environ(), "LANG=C.UTF-8")
+	setProcessGroup(s.cmd)
+	s.cmd.Stderr = &s.stderr
+	stdout, err := s.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	s.stdout = stdout
+	if err := s.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("启动 7z 失败: %v", err)
+	}
+	return s, nil
+}
+
+func (s *sevenZipStream) Read(p []byte) (int, error) {
+	n, err := s.stdout.Read(p)
+	if err == io.EOF {
+		s.eof = true
+	}
+	return n, err
+}
+
+// Close 结束 7z 进程；数据读完时按退出码报告解压错误（如主密码错误、文件损坏）
+func (s *sevenZipStream) Close() error {
+	if !s.eof {
+		// 提前停止读取（如已找到密码或已取消），直接结束进程
+		killProcessTree(s.cmd.Process)
+		s.cmd.Wait()
+		return nil
+	}
+	err := s.cmd.Wait()
+	if err == nil {
+		return nil
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+	out := sevenZipOutput{Stderr: s.stderr.String(), ExitCode: exitErr.ExitCode()}
+	if result := classify7zResult(out.ExitCode, out.Stderr); result != RESULT_OK {
+		return &archiveError{Result: result, Detail: out.errorDetail()}
+	}
+	return nil
+}
+
+// zstdDictStream 7z 解压的 zstd 字典流
+// 官方 7-Zip 与 p7zip 不支持 -tzstd，把它们的命令行错误换成明确的提示
+type zstdDictStream struct {
+	io.ReadCloser
+}
+
+func (s *zstdDictStream) Close() error {
+	err := s.ReadCloser.Close()
+	var archiveErr *archiveError
+	if errors.As(err, &archiveErr) && strings.Contains(archiveErr.Detail, "Unsupported archive type") {
+		return fmt.Errorf("当前的 7z 不支持 zstd，zstd 字典需要 7-Zip-zstd（或改用 .gz 字典）")
+	}
+	return err
+}
+
+// 函数说明：获取 7z 字典的主密码（未加密时为空），每次运行只询问一次
+// 参数：
+// path: 7z 字典路径
+// 返回：主密码，错误信息
+func getDict7zPassword(path string) (string, error) {
+	if password, ok := lookupDict7zSecret(path); ok {
+		return password, nil
+	}
+	dictPromptMu.Lock()
+	defer dictPromptMu.Unlock()
+	// 等待提示期间可能已由其他线程解锁
+	if password, ok := lookupDict7zSecret(path); ok {
+		return password, nil
+	}
+
+	// 未加密的 7z 字典无需主密码
+	entries, result := listArchive(context.Background(), path, "")
+	needPassword := result == RESULT_WRONG_PASSWORD
+	for _, entry := range entries {
+		needPassword = needPassword || entry.Encrypted
+	}
+	if !needPassword {
+		dictSecretsMu.Lock()
+		dict7zSecrets[path] = ""
+		dictSecretsMu.Unlock()
+		return "", nil
+	}
+
+	verifier := newSevenZipVerifier(path)
+	for i := 0; i < dictPassphraseAttempts; i++ {
+		password := readDictPassphrase(stdinReader, fmt.Sprintf("请输入字典 %s 的主密码: ", path))
+		if password == "" {
+			break
+		}
+		if verifier.testPassword(context.Background(), password) == RESULT_OK {
+			dictSecretsMu.Lock()
+			dict7zSecrets[path] = password
+			dictSecretsMu.Unlock()
+			return password, nil
+		}
+		fmt.Println("主密码错误")
+	}
+	return "", fmt.Errorf("未能解锁字典 %s", path)
+}
+
+// 函数说明：获取已解锁的密码库，每次运行只询问一次主密码
+// 参数：
+// path: 密码库路径
+// 返回：密码库，错误信息
+func getUnlockedVault(path string) (*passwordVault, error) {
+	if vault, ok := lookupUnlockedVault(path); ok {
+		return vault, nil
+	}
+	dictPromptMu.Lock()
+	defer dictPromptMu.Unlock()
+	if vault, ok := lookupUnlockedVault(path); ok {
+		return vault, nil
+	}
+
+	for i := 0; i < dictPassphraseAttempts; i++ {
+		passphrase := readDictPassphrase(stdinReader, fmt.Sprintf("请输入密码库 %s 的主密码: ", path))
+		if passphrase == "" {
+			break
+		}
+		vault, err := unlockVault(path, passphrase)
+		if err == nil {
+			dictSecretsMu.Lock()
+			unlockedVaults[path] = vault
+			dictSecretsMu.Unlock()
+			return vault, nil
+		}
+		fmt.Println(err)
+	}
+	return nil, fmt.Errorf("未能解锁密码库 %s", path)
+}
+
+// 函数说明：获取程序目录中正在使用的密码库（新密码保存到其中），需要时询问主密码
+// 返回：密码库（没有密码库时为 nil），错误信息
+func getActiveVault() (*passwordVault, error) {
+	path := getPasswordSavePath()
+	if !strings.EqualFold(filepath.Ext(path), ".vault") {
+		return nil, nil
+	}
+	return getUnlockedVault(path)
+}
+
+// 函数说明：从控制台读取一行主密码（直接回车放弃）
+// 在终端中输入时不回显；输入被重定向时从 reader 读取一行
+// 参数：
+// reader: 共享的控制台输入读取器
+// prompt: 提示
+// 返回：主密码（原样返回，不去掉首尾空格）
+func readDictPassphrase(reader *bufio.Reader, prompt string) string {
+	fmt.Print(prompt)
+	fd := int(os.Stdin.Fd())
+	if reader.Buffered() == 0 && term.IsTerminal(fd) {
+		passphrase, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return ""
+		}
+		return string(passphrase)
+	}
+	line, _ := reader.ReadString('\n')
+	return strings.TrimRight(line, "\r\n")
+}
+
+// 函数说明：新建加密密码库（--vault-create），放在程序目录，之后新找到的密码都保存到其中
+// 参数：
+// importPath: 要导入的明文字典（可为空）
+// 返回：错误信息
+func runVaultCreate(importPath string) error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("获取程序路径失败: %v", err)
+	}
+	vaultPath := filepath.Join(filepath.Dir(exePath), VAULT_FILE_NAME)
+	if _, err := os.Stat(vaultPath); err == nil {
+		return fmt.Errorf("%s 已存在", vaultPath)
+	}
+
+	var source io.ReadCloser
+	if importPath != "" {
+		if source, err = openPasswordFile(importPath); err != nil {
+			return fmt.Errorf("打开要导入的字典失败: %v", err)
+		}
+		defer source.Close()
+	}
+
+	passphrase := readDictPassphrase(stdinReader, "请设置密码库的主密码: ")
+	if passphrase == "" {
+		return fmt.Errorf("主密码不能为空")
+	}
+	if readDictPassphrase(stdinReader, "请再次输入主密码: ") != passphrase {
+		return fmt.Errorf("两次输入的主密码不一致")
+	}
+
+	var r io.Reader
+	if source != nil {
+		r = source
+	}
+	count, err := createVault(vaultPath, passphrase, r)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已创建密码库 %s，导入 %d 个密码\n", vaultPath, count)
+	fmt.Println("之后新找到的密码将保存到密码库中；确认无误后可删除明文字典")
+	return nil
+}
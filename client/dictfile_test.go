@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// 官方 7-Zip 不支持 -tzstd 时提示需要 7-Zip-zstd
+func TestZstdDictUnsupported(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("用 shell 脚本模拟 7z")
+	}
+	dir := t.TempDir()
+	fake7z := filepath.Join(dir, "7z")
+	script := "#!/bin/sh\necho 'Command Line Error:' >&2\necho 'Unsupported archive type : zstd' >&2\nexit 7\n"
+	if err := os.WriteFile(fake7z, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	saved := *sevenZip
+	sevenZip.path = fake7z
+	defer func() { *sevenZip = saved }()
+
+	path := filepath.Join(dir, "passwd.txt.zst")
+	if err := os.WriteFile(path, []byte("zstd data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := loadPasswordDict([]string{path})
+	if err == nil || !strings.Contains(err.Error(), "7-Zip-zstd") {
+		t.Errorf("返回 %v，应提示需要 7-Zip-zstd", err)
+	}
+}
@@ -2,19 +2,24 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
 // 函数说明：处理解压文件
 // 参数：
+// ctx: 上下文（取消时终止解压）
 // archivePath: 压缩文件路径
 // extractPath: 解压路径
 // password: 密码
 // isFound: 是否找到密码
-func handleExtract(archivePath string, extractPath string, password string, isFound bool) {
+func handleExtract(ctx context.Context, archivePath string, extractPath string, password string, isFound bool) {
 	if isFound {
 		if password == "" {
 			fmt.Println("\n文件无密码")
@@ -26,119 +31,213 @@ func handleExtract(archivePath string, extractPath string, password string, isFo
 	}
 
 	fmt.Println("正在解压文件...")
-	if err := extractArchive(archivePath, password, extractPath); err != nil {
-		fmt.Printf("解压失败: %v\n", err)
+	if err := extractArchive(ctx, archivePath, password, extractPath); err != nil {
+		if ctx.Err() == nil {
+			printExtractError(err)
+		}
 	} else {
 		fmt.Printf("\n解压成功！\n")
 		fmt.Printf("文件已保存到: %s\n", formatPath(extractPath))
+		// 记录成功次数，下次优先尝试该密码
+		if err := recordPasswordHit(password); err != nil {
+			fmt.Println(err)
+		}
+		// 按文件指纹记住该压缩包的密码，重新下载或改名后可直接解压
+		if err := recordKnownPassword(archivePath, password); err != nil {
+			fmt.Println(err)
+		}
 	}
 }
 
 // 函数说明：解压函数
 // 参数：
+// ctx: 上下文（取消时终止解压，删除本次新建的解压目录）
 // archivePath: 压缩文件路径
 // password: 密码
 // extractPath: 解压路径
-// 返回：错误信息
-func extractArchive(archivePath string, password string, extractPath string) error {
+// 返回：错误信息（被取消时为 context.Canceled）
+func extractArchive(ctx context.Context, archivePath string, password string, extractPath string) error {
 
 	// 如果解压目录不存在，则创建解压目录
+	created := false
 	if _, err := os.Stat(extractPath); os.IsNotExist(err) { // 如果解压目录不存在
 		if err := os.MkdirAll(extractPath, 0755); err != nil { // 创建解压目录
 			return fmt.Errorf("创建解压目录失败: %v", err) //
 		}
+		created = true
 	}
 
-	sevenZPath := getSevenZipPath()
-
-	args := []string{
-		"x",
-		"-y",
-		format7zPasswordArg(password),
-		fmt.Sprintf("-o%s", extractPath),
-		archivePath,
+	// 解压目录不为空：先列出同名文件，按设置的处理方式决定如何解压
+	policy := getConflictPolicy()
+	targetPath := extractPath
+	keepStaging := false
+	if !created && !isDirEmpty(extractPath) {
+		if policy == CONFLICT_ABORT {
+			return fmt.Errorf("解压目录不为空，未解压（同名文件处理方式: %s）: %s", policy, formatPath(extractPath))
+		}
+		conflicts, listed := findExtractConflicts(ctx, archivePath, password, extractPath)
+		printConflictSummary(conflicts, listed, policy)
+		if policy != CONFLICT_OVERWRITE && (len(conflicts) > 0 || !listed) {
+			// 先解压到解压目录内的临时目录，完成后再合并，已有文件在合并前不会被改动
+			staging, err := os.MkdirTemp(extractPath, stagingDirPrefix)
+			if err != nil {
+				return fmt.Errorf("创建临时解压目录失败: %v", err)
+			}
+			defer func() {
+				if !keepStaging {
+					os.RemoveAll(staging)
+				}
+			}()
+			targetPath = staging
+		}
 	}
 
-	cmd := exec.Command(sevenZPath, args...)
-	done := make(chan bool)
 	startTime := time.Now()
 
-	// 启动进度显示
-	go func() {
-		for {
-			select {
-			case <-done:
-				return
-			default:
-				elapsed := time.Since(startTime)
-				fmt.Printf("\r解压中，请稍等... 已用时: %s", formatDuration(elapsed))
-				time.Sleep(time.Second)
+	// 执行解压，显示进度条（百分比、速度、剩余时间和当前文件）
+	fmt.Print("解压中，请稍等...")
+	var last ExtractProgress
+	showProgress := consoleProgress()
+	err := selectArchiveBackend(archivePath).Extract(ctx, archivePath, password, targetPath, func(p ExtractProgress) {
+		last = p
+		showProgress(p)
+	})
+	fmt.Println()
+
+	// 被取消：解压进程已终止，已解出的文件可能不完整
+	if ctx.Err() != nil {
+		fmt.Printf("\n已取消解压: 已处理 %d 个文件（%s），用时 %s\n", last.Files, formatFileSize(last.Bytes), formatDuration(time.Since(startTime)))
+		if targetPath != extractPath {
+			fmt.Printf("已删除临时解压目录，解压目录中的已有文件未改动: %s\n", formatPath(extractPath))
+		} else if created {
+			if err := os.RemoveAll(extractPath); err != nil {
+				fmt.Printf("删除未完成的解压目录失败: %v\n", err)
+			} else {
+				fmt.Printf("已删除未完成的解压目录: %s\n", formatPath(extractPath))
 			}
+		} else {
+			fmt.Printf("解压目录在解压前已存在，未删除，其中可能有不完整的文件: %s\n", formatPath(extractPath))
 		}
-	}()
-
-	// 执行命令
-	err := cmd.Run()
+		return ctx.Err()
+	}
+	// 解压失败：不显示完成，也不上报密码（解压到临时目录时丢弃临时目录）
+	if err != nil {
+		return err
+	}
 
-	// 停止进度显示
-	done <- true
-	fmt.Println()
+	// 解压到了临时目录：按处理方式合并到解压目录
+	if targetPath != extractPath {
+		var stats conflictStats
+		if err := mergeExtracted(targetPath, extractPath, policy, &stats); err != nil {
+			// 保留临时目录，其中是尚未合并的文件
+			keepStaging = true
+			fmt.Printf("未合并的文件保留在临时解压目录: %s\n", formatPath(targetPath))
+			return fmt.Errorf("合并解压文件失败: %v", err)
+		}
+		printMergeSummary(stats, policy)
+	}
 
 	// 显示总用时
 	totalTime := time.Since(startTime)
 	fmt.Printf("\n解压完成，总用时: %s\n", formatDuration(totalTime))
 	reportPassword(archivePath, password)
 
-	if err != nil {
-		return fmt.Errorf("解压失败: %v", err)
-	}
-
 	return nil
 }
 
 // 处理密码破解失败的情况
 // 参数：
+// ctx: 上下文（取消后不再询问）
 // archivePath: 压缩文件路径
 // extractPath: 解压路径
 // reader: 输入读取器（用于读取含空格的密码）
-func handleCrackFailed(archivePath string, extractPath string, reader *bufio.Reader) {
+func handleCrackFailed(ctx context.Context, archivePath string, extractPath string, reader *bufio.Reader) {
 	fmt.Println("\n密码破解失败！")
+	verifier := newPasswordVerifier(archivePath)
 
-	for {
-		fmt.Print("请输入新的密码，右键直接粘贴(直接回车退出): ")
-		var password string
-
-		// 检测右键点击
-		state, _, _ := getAsyncKeyState.Call(uintptr(VK_CONTROL))
-		if state&0x8000 != 0 {
-			// 如果按下了 Ctrl，等待右键点击
-			time.Sleep(100 * time.Millisecond)
-			state, _, _ = getAsyncKeyState.Call(uintptr(WM_RBUTTONDOWN))
-			if state&0x8000 != 0 {
-				// 获取剪贴板内容
-				password = getClipboardText()
-				fmt.Println(password) // 显示粘贴的内容
-			}
-		} else {
-			// 普通输入（使用 readLineInput 支持密码中含空格）
-			password = readLineInput(reader)
-		}
+	for ctx.Err() == nil {
+		fmt.Print("请输入新的密码，右键直接粘贴（输入 /mask 使用掩码暴力破解，直接回车退出）: ")
+		// 普通输入支持密码中含空格，Ctrl+右键直接粘贴
+		password := readInputOrPaste(reader)
 
 		if password == "" {
 			return
 		}
-
-		if testPassword(archivePath, password) {
-			handleExtract(archivePath, extractPath, password, true)
-			//保存密码到passwd.txt文件
-			if err := savePasswordToFile(password); err != nil {
-				fmt.Printf("保存密码失败: %v\n", err)
-			} else {
-				fmt.Printf("新密码【%s】已保存到passwd.txt文件。 \n", password)
+		if password == "/mask" {
+			if runMaskAttack(ctx, archivePath, extractPath, reader) {
+				return
 			}
+			continue
+		}
+
+		result := verifier.testPassword(ctx, password)
+		if ctx.Err() != nil {
 			return
-		} else {
+		}
+		if result == RESULT_OK {
+			handleExtract(ctx, archivePath, extractPath, password, true)
+			saveNewPassword(password)
+			return
+		} else if result == RESULT_WRONG_PASSWORD {
 			fmt.Println("\n密码错误！请重试或回车退出")
+		} else if result == RESULT_UNKNOWN {
+			fmt.Printf("\n测试超过 %s 仍未得出结论，无法确认密码是否正确！请重试或回车退出\n", formatDuration(verifyTimeout))
+		} else {
+			fmt.Printf("\n%s！%s\n", getResultDesc(result), getResultHint(result))
+			return
+		}
+	}
+}
+
+// 函数说明：交互式掩码暴力破解（字典破解失败后使用）
+// 参数：
+// ctx: 上下文
+// archivePath: 压缩文件路径
+// extractPath: 解压路径
+// reader: 输入读取器
+// 返回：是否结束（找到密码、被取消或压缩包有与密码无关的问题），false 时回到手动输入
+func runMaskAttack(ctx context.Context, archivePath, extractPath string, reader *bufio.Reader) bool {
+	spec, ok := readMaskSpec(reader)
+	if !ok {
+		return false
+	}
+	source, err := newMaskSource(spec)
+	if err != nil {
+		fmt.Printf("掩码无效: %v\n", err)
+		return false
+	}
+
+	fmt.Println(formatMaskInfo(spec, source))
+	fmt.Print("是否开始? (y/n) [Y]: ")
+	if answer := strings.ToLower(readLineInput(reader)); answer != "" && answer != "y" && answer != "yes" {
+		return false
+	}
+
+	err = crackAndExtract(ctx, archivePath, extractPath, source, reader, true)
+	var archiveErr *archiveError
+	if err != nil && ctx.Err() == nil && !errors.As(err, &archiveErr) {
+		fmt.Println("\n掩码破解未找到密码")
+		return false
+	}
+	return true
+}
+
+// saveNewPassword 把字典以外找到的密码保存到 passwd.txt（或密码库），下次直接命中
+func saveNewPassword(password string) {
+	if added, err := savePasswordToFile(password); err != nil {
+		fmt.Printf("保存密码失败: %v\n", err)
+	} else if added {
+		fmt.Printf("新密码【%s】已保存到%s文件。 \n", password, filepath.Base(getPasswordSavePath()))
+	}
+}
+
+// printExtractError 按失败类型打印解压错误及处理建议
+func printExtractError(err error) {
+	fmt.Printf("解压失败: %v\n", err)
+	var archiveErr *archiveError
+	if errors.As(err, &archiveErr) {
+		if hint := getResultHint(archiveErr.Result); hint != "" {
+			fmt.Println(hint)
 		}
 	}
 }
@@ -146,11 +245,14 @@ func handleCrackFailed(archivePath string, extractPath string, reader *bufio.Rea
 //	函数说明：处理压缩文件
 //
 // 参数：
+// ctx: 上下文，取消（Ctrl+C）时停止破解或解压，并显示已完成的部分
 // archivePath: 压缩文件路径
-// passwords: 密码列表
-// passwordsInfo: 使用的密码文件信息
 // reader: 输入读取器（用于密码输入等，可为 nil）
-func processArchive(archivePath string, passwords []string, passwordsInfo string, reader *bufio.Reader) {
+func processArchive(ctx context.Context, archivePath string, reader *bufio.Reader) {
+	if reader == nil {
+		reader = stdinReader
+	}
+
 	// 获取文件信息
 	fileInfo, err := os.Stat(archivePath)
 	if err != nil {
@@ -165,6 +267,11 @@ func processArchive(archivePath string, passwords []string, passwordsInfo string
 	fileType := getFileType(archivePath)
 	fmt.Printf("文件类型: %s\n", getFileTypeDesc(fileType))
 
+	// 7z 被杀毒软件删除或系统中未安装时，后面的测试和解压都会失败，先提示
+	if _, err := exec.LookPath(getSevenZipPath()); err != nil {
+		fmt.Printf("警告：无法使用 7z（%v），请安装 7-Zip（Linux 上为 7zz、7z 或 7za）\n", err)
+	}
+
 	// 获取解压路径
 	extractPath := getDefaultExtractPath(archivePath)
 
@@ -183,8 +290,10 @@ func processArchive(archivePath string, passwords []string, passwordsInfo string
 	// 检查是否需要密码
 	if !isPasswordRequired(fileType) {
 		fmt.Println("检测到无需密码的文件格式，直接解压...")
-		if err := extractArchive(archivePath, "", extractPath); err != nil {
-			fmt.Printf("解压失败: %v\n", err)
+		if err := extractArchive(ctx, archivePath, "", extractPath); err != nil {
+			if ctx.Err() == nil {
+				printExtractError(err)
+			}
 		} else {
 			fmt.Printf("\n解压成功！\n")
 			fmt.Printf("文件已保存到: %s\n", formatPath(extractPath))
@@ -192,20 +301,85 @@ func processArchive(archivePath string, passwords []string, passwordsInfo string
 		return
 	}
 
-	// 需要密码的文件处理逻辑
+	// 同一个压缩包以前解压成功过时，直接使用记住的密码
+	if password, ok := findKnownPassword(ctx, archivePath); ok {
+		handleExtract(ctx, archivePath, extractPath, password, false)
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	// 开启服务器查询时，先试其他用户为同一个压缩包找到的密码
+	passwords := findServerPasswords(ctx, archivePath)
+	if ctx.Err() != nil {
+		return
+	}
 	if len(passwords) > 0 {
-		fmt.Println(passwordsInfo)
+		fmt.Printf("\n服务器上有 %d 个该压缩包的密码，先尝试...\n", len(passwords))
+		if password, ok := tryPasswords(ctx, archivePath, passwords); ok {
+			handleExtract(ctx, archivePath, extractPath, password, true)
+			saveNewPassword(password)
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Println("服务器上的密码均不正确，继续破解")
+	}
+
+	// 压缩包注释常写有密码或密码的出处，破解前先显示
+	comment := readArchiveComment(ctx, archivePath)
+	if comment != "" {
+		fmt.Printf("\n压缩包注释:\n%s\n", comment)
+	}
+
+	// 需要密码的文件处理逻辑
+	source, saveFound := newAttackSource(archivePath)
+
+	// 注释、文件名和同目录说明文件中写明的密码最先尝试
+	if harvested := harvestPasswords(archivePath, comment); len(harvested) > 0 {
+		fmt.Println(formatHarvestInfo(harvested))
+		source = newChainSource(newListSource(harvestedPasswordList(harvested)), source)
+		saveFound = true
 	}
+	err = crackAndExtract(ctx, archivePath, extractPath, source, reader, saveFound)
+	var archiveErr *archiveError
+	if err != nil && ctx.Err() == nil && !errors.As(err, &archiveErr) {
+		handleCrackFailed(ctx, archivePath, extractPath, reader)
+	}
+}
+
+// 函数说明：用候选来源破解压缩文件，找到密码后解压
+// 参数：
+// ctx: 上下文（取消时保存破解进度）
+// archivePath: 压缩文件路径
+// extractPath: 解压路径
+// source: 候选密码来源
+// reader: 输入读取器
+// saveFound: 找到的密码是否保存到 passwd.txt（含字典以外的来源时，已在字典中的不重复保存）
+// 返回：错误信息（与密码无关的问题为 *archiveError，已显示给用户；被取消时为 context.Canceled）
+func crackAndExtract(ctx context.Context, archivePath, extractPath string, source candidateSource, reader *bufio.Reader, saveFound bool) error {
+	// 同一个压缩包上次未破解完时，询问是否从上次的进度继续
+	session := loadCrackSession(archivePath, source)
+	offerResumeSession(session, reader)
+
 	fmt.Println("\n开始尝试破解...")
 
 	// 尝试使用找到的密码解压
-	if foundPassword, err := crackArchive(archivePath, passwords); err == nil {
-		handleExtract(archivePath, extractPath, foundPassword, true)
-	} else {
-		if reader != nil {
-			handleCrackFailed(archivePath, extractPath, reader)
-		} else {
-			handleCrackFailed(archivePath, extractPath, bufio.NewReader(os.Stdin))
+	foundPassword, err := crackArchive(ctx, archivePath, source, session)
+	var archiveErr *archiveError
+	if err == nil {
+		handleExtract(ctx, archivePath, extractPath, foundPassword, true)
+		if saveFound && foundPassword != "" {
+			saveNewPassword(foundPassword)
+		}
+	} else if errors.As(err, &archiveErr) {
+		// 与密码无关的问题（缺少分卷、数据损坏等），手动输入密码也无济于事
+		fmt.Printf("\n无法破解: %v\n", archiveErr)
+		if hint := getResultHint(archiveErr.Result); hint != "" {
+			fmt.Println(hint)
 		}
 	}
+	return err
 }
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// 解压成功后才上报密码，解压失败时不上报
+func TestExtractArchiveReportsOnlyOnSuccess(t *testing.T) {
+	dir := t.TempDir()
+	// UUID 保存在临时目录，测试时不写到真实的临时目录
+	t.Setenv("TMPDIR", dir)
+	t.Setenv("TMP", dir)
+	t.Setenv("TEMP", dir)
+
+	var reports atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reports.Add(1)
+	}))
+	defer server.Close()
+	saved := appConfig
+	defer func() { appConfig = saved }()
+	appConfig.ServerURL, appConfig.AppKey, appConfig.AppSecret = server.URL, testAppKey, testAppSecret
+
+	items := []tarItem{{name: "hello.txt", typeflag: tar.TypeReg, body: strings.Repeat("hello\n", 1000)}}
+	good := filepath.Join(dir, "good.tar")
+	writeTestTar(t, good, items)
+	if err := extractArchive(context.Background(), good, "", filepath.Join(dir, "good")); err != nil {
+		t.Fatal(err)
+	}
+	if n := reports.Load(); n != 1 {
+		t.Fatalf("解压成功后上报了 %d 次，应为 1 次", n)
+	}
+
+	// 文件内容被截断的 tar
+	bad := filepath.Join(dir, "bad.tar")
+	writeTestTar(t, bad, items)
+	if err := os.Truncate(bad, 2048); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractArchive(context.Background(), bad, "", filepath.Join(dir, "bad")); err == nil {
+		t.Fatal("截断的压缩包应解压失败")
+	}
+	if n := reports.Load(); n != 1 {
+		t.Errorf("解压失败后仍上报了密码")
+	}
+}
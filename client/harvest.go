@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 同目录说明文件的扫描限制：只看小文件，避免把大文本当成说明文件
+const (
+	harvestMaxFileSize = 64 * 1024
+	harvestMaxFiles    = 20
+	harvestMaxLen      = 64 // 超过该长度的匹配结果不像密码
+)
+
+// harvestFileExts 可能写有密码的同目录文件
+var harvestFileExts = []string{".txt", ".url", ".nfo"}
+
+// DEFAULT_HARVEST_PATTERNS 默认的密码提示正则，第一个非空的捕获组即为密码
+// 可在 7zrpw.json 的 harvest_patterns 中替换
+var DEFAULT_HARVEST_PATTERNS = []string{
+	// 解压密码：xxx、密码是：xxx、解压码【xxx】；关键词后必须有分隔符或括号引号，「密码错误」不算
+	`(?:密码|密碼|口令|解压码|解壓碼)\s*(?:是|为|為)?\s*(?:[:：=]\s*[【\[「『（("“']?|[【\[「『（("“'])\s*([^\s【】\[\]「」『』()（）"“”'，,；;。]+)`,
+	// password: xxx、pwd_xxx、password is "xxx"；关键词后必须有分隔符或括号引号，「pass the file」不算
+	`(?i)(?:^|[^a-z])(?:password|passwd|pwd|pass|pw)(?:_|(?:\s+is)?\s*(?:[:：=]\s*[\[(【"']?|[\[(【"']))\s*([^\s\[\]()【】"',;]+)`,
+}
+
+// harvestedPassword 从压缩包周围找到的候选密码
+type harvestedPassword struct {
+	Password string
+	Source   string // 来源：文件名、压缩包注释或说明文件名
+}
+
+// getHarvestPatterns 编译密码提示正则，配置中无效的正则提示后忽略
+func getHarvestPatterns() []*regexp.Regexp {
+	patterns := DEFAULT_HARVEST_PATTERNS
+	if len(appConfig.HarvestPatterns) > 0 {
+		patterns = appConfig.HarvestPatterns
+	}
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			fmt.Printf("密码提示正则无效，已忽略: %s (%v)\n", pattern, err)
+			continue
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled
+}
+
+// 函数说明：用密码提示正则从一段文本中提取候选密码
+// 参数：
+// text: 文本
+// patterns: 密码提示正则
+// 返回：候选密码（按出现顺序，可能重复）
+func matchHarvestPatterns(text string, patterns []*regexp.Regexp) []string {
+	var passwords []string
+	for _, re := range patterns {
+		for _, match := range re.FindAllStringSubmatch(text, -1) {
+			for _, group := range match[1:] {
+				password := strings.TrimSpace(group)
+				if password != "" && utf8.RuneCountInString(password) <= harvestMaxLen {
+					passwords = append(passwords, password)
+					break
+				}
+			}
+		}
+	}
+	return passwords
+}
+
+// 函数说明：从压缩包的文件名、注释和同目录的说明文件（.txt/.url/.nfo）中收集候选密码
+// 参数：
+// archivePath: 压缩文件路径
+// comment: 压缩包注释（可为空）
+// 返回：去重后的候选密码，按注释、文件名、说明文件的顺序排列
+func harvestPasswords(archivePath string, comment string) []harvestedPassword {
+	patterns := getHarvestPatterns()
+	var harvested []harvestedPassword
+	seen := make(map[string]bool)
+	addPassword := func(password, source string) {
+		if !seen[password] {
+			seen[password] = true
+			harvested = append(harvested, harvestedPassword{Password: password, Source: source})
+		}
+	}
+	add := func(text, source string) {
+		for _, password := range matchHarvestPatterns(text, patterns) {
+			addPassword(password, source)
+		}
+	}
+
+	// 注释中的提示和像密码的词优先级最高，其次是文件名（去掉扩展名和分卷标识）
+	if comment != "" {
+		add(comment, "压缩包注释")
+		for _, token := range extractCommentTokens(comment) {
+			addPassword(token, "压缩包注释")
+		}
+	}
+	add(filepath.Base(getDefaultExtractPath(archivePath)), "文件名")
+
+	dir := filepath.Dir(archivePath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return harvested
+	}
+	scanned := 0
+	for _, entry := range entries {
+		if scanned >= harvestMaxFiles {
+			break
+		}
+		name := entry.Name()
+		if entry.IsDir() || !isHarvestFile(name) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.Size() > harvestMaxFileSize {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		scanned++
+		add(decodeGBK(string(data)), decodeGBK(name))
+	}
+	return harvested
+}
+
+// isHarvestFile 判断同目录文件是否可能是说明文件（密码字典本身除外）
+func isHarvestFile(name string) bool {
+	for _, passwdName := range PASSWD_FILE_NAMES {
+		if strings.EqualFold(name, passwdName) {
+			return false
+		}
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, harvestExt := range harvestFileExts {
+		if ext == harvestExt {
+			return true
+		}
+	}
+	return false
+}
+
+// harvestedPasswordList 取出候选密码
+func harvestedPasswordList(harvested []harvestedPassword) []string {
+	passwords := make([]string, len(harvested))
+	for i, h := range harvested {
+		passwords[i] = h.Password
+	}
+	return passwords
+}
+
+// formatHarvestInfo 显示找到的候选密码及来源
+func formatHarvestInfo(harvested []harvestedPassword) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "从文件名、注释和说明文件中找到 %d 个可能的密码，最先尝试:", len(harvested))
+	for _, h := range harvested {
+		fmt.Fprintf(&sb, "\n  %s（%s）", h.Password, h.Source)
+	}
+	return sb.String()
+}
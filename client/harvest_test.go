@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestMatchHarvestPatterns(t *testing.T) {
+	var patterns []*regexp.Regexp
+	for _, pattern := range DEFAULT_HARVEST_PATTERNS {
+		patterns = append(patterns, regexp.MustCompile(pattern))
+	}
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"解压密码：abc123", []string{"abc123"}},
+		{"资料 解压密码: abc123，请勿外传", []string{"abc123"}},
+		{"密碼=abc123", []string{"abc123"}},
+		{"密码是：abc123", []string{"abc123"}},
+		{"解压码【abc123】", []string{"abc123"}},
+		{"口令「abc123」", []string{"abc123"}},
+		{"xxx_pwd_abc123", []string{"abc123"}},
+		{"password: secret", []string{"secret"}},
+		{"Password = 'secret'", []string{"secret"}},
+		{"PWD:secret", []string{"secret"}},
+		{`the password is "secret"`, []string{"secret"}},
+		// 关键词后没有分隔符的不是密码提示
+		{"密码错误", nil},
+		{"密码是否正确", nil},
+		{"忘记密码 请联系管理员", nil},
+		{"pass the file", nil},
+		{"password is wrong", nil},
+		{"passport: abc", nil},
+		{"bypass: abc", nil},
+		{"compass=north", nil},
+	}
+	for _, tt := range tests {
+		if got := matchHarvestPatterns(tt.text, patterns); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: 提取出 %q，应为 %q", tt.text, got, tt.want)
+		}
+	}
+}
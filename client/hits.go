@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// passwordHit 一个密码的成功记录
+type passwordHit struct {
+	Count       int       `json:"count"`        // 成功解压的次数
+	LastSuccess time.Time `json:"last_success"` // 最近一次成功的时间
+}
+
+// getHitsPath 获取成功记录文件路径（程序目录，与 passwd.txt 同目录；有密码库时加密保存为 passwd_hits.json.enc）
+func getHitsPath() string {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "passwd_hits.json"
+	}
+	return filepath.Join(filepath.Dir(exePath), "passwd_hits.json")
+}
+
+// loadPasswordHits 读取各密码的成功记录，文件不存在、损坏或无法解密时返回空记录
+func loadPasswordHits() map[string]passwordHit {
+	hits, _ := readPasswordHits()
+	return hits
+}
+
+// readPasswordHits 读取各密码的成功记录，文件不存在或损坏时返回空记录，无法读取（如密码库未能解锁）时返回错误
+func readPasswordHits() (map[string]passwordHit, error) {
+	hits := make(map[string]passwordHit)
+	data, err := readRecordFile(getHitsPath())
+	if err != nil {
+		return hits, err
+	}
+	json.Unmarshal(data, &hits)
+	return hits, nil
+}
+
+// 函数说明：记录一次密码成功（解压成功后调用）
+// 参数：
+// password: 密码
+// 返回：错误信息
+func recordPasswordHit(password string) error {
+	if password == "" {
+		return nil
+	}
+	// 读取失败时不保存，以免用只有本次记录的内容覆盖已有记录
+	hits, err := readPasswordHits()
+	if err != nil {
+		return fmt.Errorf("保存密码成功记录失败: %v", err)
+	}
+	hit := hits[password]
+	hit.Count++
+	hit.LastSuccess = time.Now()
+	hits[password] = hit
+
+	data, err := json.MarshalIndent(hits, "", "  ")
+	if err != nil {
+		return fmt.Errorf("保存密码成功记录失败: %v", err)
+	}
+	if err := writeRecordFile(getHitsPath(), data); err != nil {
+		return fmt.Errorf("保存密码成功记录失败: %v", err)
+	}
+	return nil
+}
+
+// 函数说明：按成功次数排序密码，成功次数多的先试
+// 次数相同时最近成功的在前，从未成功过的保持字典文件中的顺序
+// 参数：
+// passwords: 按文件顺序排列的密码列表（原地排序）
+// hits: 成功记录
+func sortPasswordsByHits(passwords []string, hits map[string]passwordHit) {
+	sort.SliceStable(passwords, func(i, j int) bool {
+		a, b := hits[passwords[i]], hits[passwords[j]]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.LastSuccess.After(b.LastSuccess)
+	})
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+)
+
+// hybridSpec 字典 + 掩码混合攻击参数
+type hybridSpec struct {
+	Mask maskSpec // 与字典中每个密码组合的掩码
+	Left bool     // 掩码在左侧（如日期前缀 ?d?d?d?d+密码），否则在右侧（密码+?d?d?d）
+}
+
+// hybridAttack 当前使用的混合攻击（Mask.Mask 为空表示不使用），来自命令行或交互菜单
+var hybridAttack hybridSpec
+
+// hybridSource 把字典中的每个密码与掩码生成的每个片段组合
+// 外层按字典顺序、内层按掩码顺序：字典只需流式读一遍，曾经成功过的密码的组合最先尝试
+type hybridSource struct {
+	dict *passwordDict
+	mask *maskSource
+	left bool
+	it   *dictIterator
+	word string // 当前组合的字典密码
+	part int64  // 当前密码下一个要组合的掩码片段位置
+}
+
+// 函数说明：创建字典 + 掩码混合候选来源
+// 参数：
+// dict: 字典
+// spec: 混合攻击参数
+// 返回：候选来源，错误信息
+func newHybridSource(dict *passwordDict, spec hybridSpec) (*hybridSource, error) {
+	mask, err := newMaskSource(spec.Mask)
+	if err != nil {
+		return nil, err
+	}
+	if dict.count > 0 && mask.total() > math.MaxInt64/dict.count {
+		return nil, fmt.Errorf("字典与掩码 %q 组合后的密码空间过大", spec.Mask.Mask)
+	}
+	return &hybridSource{
+		dict: dict,
+		mask: mask,
+		left: spec.Left,
+		it:   dict.iterate(),
+		part: mask.total(), // 第一次调用 next 时读取第一个密码
+	}, nil
+}
+
+func (s *hybridSource) next() (string, bool) {
+	if s.part >= s.mask.total() {
+		word, ok := s.it.next()
+		if !ok {
+			return "", false
+		}
+		s.word, s.part = word, 0
+	}
+	part := s.mask.at(s.part)
+	s.part++
+	if s.left {
+		return part + s.word, true
+	}
+	return s.word + part, true
+}
+
+func (s *hybridSource) total() int64 {
+	return s.dict.count * s.mask.total()
+}
+
+func (s *hybridSource) fingerprint() string {
+	side := "right"
+	if s.left {
+		side = "left"
+	}
+	h := sha256.New()
+	h.Write([]byte(s.dict.fingerprint))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(s.mask.fingerprint()))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(side))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *hybridSource) dicts() []*passwordDict {
+	return []*passwordDict{s.dict}
+}
+
+func (s *hybridSource) close() {
+	s.it.closeFile()
+}
+
+func (s *hybridSource) seek(pos int64) {
+	s.it.rewind()
+	s.part = s.mask.total()
+	if pos <= 0 || s.mask.total() == 0 {
+		return
+	}
+	s.it.skip(pos / s.mask.total())
+	if offset := pos % s.mask.total(); offset > 0 {
+		if word, ok := s.it.next(); ok {
+			s.word, s.part = word, offset
+		}
+	}
+}
+
+// 函数说明：生成混合攻击的说明信息（含密码空间）
+// 参数：
+// spec: 混合攻击参数
+// source: 由 spec 创建的候选来源
+// 返回：说明信息
+func formatHybridInfo(spec hybridSpec, source *hybridSource) string {
+	layout := "字典+掩码"
+	if spec.Left {
+		layout = "掩码+字典"
+	}
+	return fmt.Sprintf("混合破解（%s）: %s\n密码空间: %d 个密码 × %d 种掩码组合 = 共 %d 个密码",
+		layout, describeMask(spec.Mask, source.mask), source.dict.count, source.mask.total(), source.total())
+}
+
+// 函数说明：交互式设置混合攻击，之后处理的文件都使用该设置，直到关闭
+// 参数：
+// reader: 输入读取器
+func configureHybridAttack(reader *bufio.Reader) {
+	fmt.Println("\n字典+掩码混合破解：把 passwd.txt 中的每个密码与掩码组合，如 密码+?d?d?d、?d?d?d?d+密码")
+	if hybridAttack.Mask.Mask != "" {
+		fmt.Printf("当前已开启，掩码: %s\n", hybridAttack.Mask.Mask)
+	}
+	fmt.Println("输入1: 掩码在右侧（字典+掩码，如 abc123）")
+	fmt.Println("输入2: 掩码在左侧（掩码+字典，如 2024abc）")
+	fmt.Println("输入0: 关闭混合破解")
+	fmt.Print("请选择 (直接回车取消): ")
+
+	var spec hybridSpec
+	switch readLineInput(reader) {
+	case "1":
+	case "2":
+		spec.Left = true
+	case "0":
+		hybridAttack = hybridSpec{}
+		fmt.Println("已关闭混合破解，使用字典破解")
+		return
+	default:
+		return
+	}
+
+	mask, ok := readMaskSpec(reader)
+	if !ok {
+		return
+	}
+	spec.Mask = mask
+
+	// 用当前的字典（不含 passwd.d 中限定了文件名的字典）计算密码空间，让用户开始前就知道规模
+	dict, _, _ := getAllPasswords("")
+	source, err := newHybridSource(dict, spec)
+	if err != nil {
+		fmt.Printf("掩码无效: %v\n", err)
+		return
+	}
+	hybridAttack = spec
+	fmt.Println(formatHybridInfo(spec, source))
+	fmt.Println("已开启混合破解，之后选择的文件都将使用该设置（再次输入 m 可关闭）")
+}
+
+// describeHybridState 交互菜单中显示的混合破解状态
+func describeHybridState() string {
+	switch {
+	case hybridAttack.Mask.Mask == "":
+		return ""
+	case hybridAttack.Left:
+		return fmt.Sprintf("（已开启: %s+字典）", hybridAttack.Mask.Mask)
+	default:
+		return fmt.Sprintf("（已开启: 字典+%s）", hybridAttack.Mask.Mask)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHybridSource(t *testing.T) {
+	// 字典中有重复，成功过的 c 最先使用
+	writeTestHits(t, map[string]passwordHit{"c": {Count: 1, LastSuccess: time.Now()}})
+	dict, err := loadPasswordDict([]string{writeTestDict(t, []string{"a", "b", "a", "c"})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		spec hybridSpec
+		want []string
+	}{
+		{hybridSpec{Mask: maskSpec{Mask: "?1", Charsets: [maskCustomCharsets]string{"12"}}},
+			[]string{"c1", "c2", "a1", "a2", "b1", "b2"}},
+		{hybridSpec{Mask: maskSpec{Mask: "?1", Charsets: [maskCustomCharsets]string{"12"}}, Left: true},
+			[]string{"1c", "2c", "1a", "2a", "1b", "2b"}},
+		{hybridSpec{Mask: maskSpec{Mask: "?1?1", Charsets: [maskCustomCharsets]string{"01"}, Increment: true}},
+			[]string{"c0", "c1", "c00", "c01", "c10", "c11", "a0", "a1", "a00", "a01", "a10", "a11", "b0", "b1", "b00", "b01", "b10", "b11"}},
+	}
+	for _, tt := range tests {
+		source, err := newHybridSource(dict, tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := drainCandidates(source)
+		source.close()
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%+v: 生成 %q，应为 %q", tt.spec, got, tt.want)
+		}
+		if source.total() != int64(len(tt.want)) {
+			t.Errorf("%+v: 密码空间为 %d，应为 %d", tt.spec, source.total(), len(tt.want))
+		}
+
+		// 从每个位置（包括两个字典密码之间和末尾之后）继续都与从头生成的对应部分一致
+		for pos := int64(0); pos <= source.total()+1; pos++ {
+			source, _ := newHybridSource(dict, tt.spec)
+			source.seek(pos)
+			rest := drainCandidates(source)
+			source.close()
+			want := tt.want[min(pos, int64(len(tt.want))):]
+			if strings.Join(rest, ",") != strings.Join(want, ",") {
+				t.Errorf("%+v: 从 %d 继续得到 %q，应为 %q", tt.spec, pos, rest, want)
+			}
+		}
+	}
+}
+
+func TestHybridSourceTooLarge(t *testing.T) {
+	dict, err := loadPasswordDict([]string{writeTestDict(t, []string{"a", "b"})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 掩码本身不超出 int64，乘以字典大小后超出
+	spec := hybridSpec{Mask: maskSpec{Mask: strings.Repeat("?d", 18) + "?1", Charsets: [maskCustomCharsets]string{"abcdefghi"}}}
+	if _, err := newHybridSource(dict, spec); err == nil {
+		t.Error("组合后的密码空间超出范围时应返回错误")
+	}
+}
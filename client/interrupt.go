@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// processWaitDelay 取消后等待 7z 进程退出、输出管道关闭的时间上限
+const processWaitDelay = 2 * time.Second
+
+// 函数说明：创建随 Ctrl+C 取消的上下文
+// 第一次 Ctrl+C 取消当前操作（终止 7z 进程树、保存破解进度、删除未完成的解压目录），再按一次立即退出
+// 参数：
+// parent: 父上下文
+// 返回：上下文，停止监听的函数（之后 Ctrl+C 恢复默认行为，即直接退出程序）
+func withInterrupt(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	stopped := make(chan struct{})
+
+	go func() {
+		select {
+		case <-signals:
+			fmt.Println("\n收到 Ctrl+C，正在取消...（再按一次立即退出）")
+			cancel()
+		case <-stopped:
+			return
+		}
+		select {
+		case <-signals:
+			os.Exit(130)
+		case <-stopped:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(stopped)
+		cancel()
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// knownArchive 一个压缩包（按文件指纹识别）确认过的密码
+// 重新下载或改名后的同一个压缩包指纹不变，可以直接用记住的密码解压
+type knownArchive struct {
+	Name        string    `json:"name"`         // 最近一次的文件名，仅供查看
+	Passwords   []string  `json:"passwords"`    // 确认过的密码，最近成功的在前
+	LastSuccess time.Time `json:"last_success"` // 最近一次成功的时间
+}
+
+// getKnownPath 获取已知密码库路径（程序目录，与 passwd.txt 同目录；有密码库时加密保存为 passwd_known.json.enc）
+func getKnownPath() string {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "passwd_known.json"
+	}
+	return filepath.Join(filepath.Dir(exePath), "passwd_known.json")
+}
+
+// fingerprintKey 已知密码库中的键：文件大小 + 前 1024 字节和前 1MB 的 MD5
+func fingerprintKey(fp fileFingerprint) string {
+	return fmt.Sprintf("%d-%s-%s", fp.Size, fp.MD5_1024, fp.MD5_1MB)
+}
+
+// loadKnownArchives 读取已知密码库，文件不存在或损坏时返回空记录，无法读取（如密码库未能解锁）时返回错误
+func loadKnownArchives() (map[string]knownArchive, error) {
+	known := make(map[string]knownArchive)
+	data, err := readRecordFile(getKnownPath())
+	if err != nil {
+		return known, err
+	}
+	json.Unmarshal(data, &known)
+	return known, nil
+}
+
+// 函数说明：记录压缩包确认过的密码（解压成功后调用）
+// 参数：
+// archivePath: 压缩文件路径（分卷时为第一个分卷）
+// password: 密码
+// 返回：错误信息
+func recordKnownPassword(archivePath, password string) error {
+	if password == "" {
+		return nil
+	}
+	fp, err := getFileFingerprint(archivePath)
+	if err != nil {
+		return fmt.Errorf("保存已知密码失败: %v", err)
+	}
+	// 读取失败时不保存，以免用只有本次记录的内容覆盖已有记录
+	known, err := loadKnownArchives()
+	if err != nil {
+		return fmt.Errorf("保存已知密码失败: %v", err)
+	}
+	key := fingerprintKey(fp)
+	entry := known[key]
+	entry.Name = filepath.Base(archivePath)
+	entry.LastSuccess = time.Now()
+	passwords := []string{password}
+	for _, pass := range entry.Passwords {
+		if pass != password {
+			passwords = append(passwords, pass)
+		}
+	}
+	entry.Passwords = passwords
+	known[key] = entry
+
+	data, err := json.MarshalIndent(known, "", "  ")
+	if err != nil {
+		return fmt.Errorf("保存已知密码失败: %v", err)
+	}
+	if err := writeRecordFile(getKnownPath(), data); err != nil {
+		return fmt.Errorf("保存已知密码失败: %v", err)
+	}
+	return nil
+}
+
+// 函数说明：查找同一个压缩包（指纹相同）以前确认过的密码并逐个测试
+// 参数：
+// ctx: 上下文
+// archivePath: 压缩文件路径（分卷时为第一个分卷）
+// 返回：正确的密码，是否找到
+func findKnownPassword(ctx context.Context, archivePath string) (string, bool) {
+	known, err := loadKnownArchives()
+	if err != nil {
+		fmt.Println(err)
+	}
+	if len(known) == 0 {
+		return "", false
+	}
+	fp, err := getFileFingerprint(archivePath)
+	if err != nil {
+		return "", false
+	}
+	entry, ok := known[fingerprintKey(fp)]
+	if !ok || len(entry.Passwords) == 0 {
+		return "", false
+	}
+
+	fmt.Printf("\n该压缩包曾经解压成功过（%s），先尝试记住的 %d 个密码...\n", entry.Name, len(entry.Passwords))
+	if password, ok := tryPasswords(ctx, archivePath, entry.Passwords); ok {
+		return password, true
+	}
+	if ctx.Err() != nil {
+		return "", false
+	}
+	fmt.Println("记住的密码均不正确，继续破解")
+	return "", false
+}
+
+// 函数说明：逐个测试少量候选密码（记住的密码、服务器查到的密码），不显示进度
+// 参数：
+// ctx: 上下文（取消后停止）
+// archivePath: 压缩文件路径
+// passwords: 候选密码
+// 返回：正确的密码，是否找到（遇到与密码无关的问题或被取消时也返回 false，交给后面的流程报告）
+func tryPasswords(ctx context.Context, archivePath string, passwords []string) (string, bool) {
+	verifier := newPasswordVerifier(archivePath)
+	for _, password := range passwords {
+		result := verifier.testPassword(ctx, password)
+		if ctx.Err() != nil {
+			return "", false
+		}
+		switch result {
+		case RESULT_OK:
+			return password, true
+		case RESULT_WRONG_PASSWORD, RESULT_UNKNOWN:
+			continue
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -35,6 +36,11 @@ func main() {
 		os.Create(passwdPath)
 	}
 
+	// 读取配置文件
+	if err := loadAppConfig(); err != nil {
+		fmt.Printf("%v，将使用默认配置\n", err)
+	}
+
 	// 检查命令行参数
 	if len(os.Args) > 1 {
 		switch os.Args[1] {
@@ -58,13 +64,41 @@ func main() {
 			fmt.Print("\n按回车键退出...")
 			fmt.Scanln()
 			return
+		case "--vault-create":
+			// 新建加密密码库，可导入明文字典：7zrpw --vault-create [passwd.txt]
+			importPath := strings.Join(os.Args[2:], " ")
+			if err := runVaultCreate(importPath); err != nil {
+				fmt.Println(err)
+			}
+			fmt.Print("\n按回车键退出...")
+			fmt.Scanln()
+			return
+		case "dict":
+			// 字典管理：7zrpw dict add/remove/dedupe/merge/stats/sort
+			if err := runDictCommand(os.Args[2:]); err != nil {
+				fmt.Println(err)
+			}
+			return
 		default:
+			// 先解析选项（如 --threads 8），剩余参数为文件路径
+			args, err := parseOptions(os.Args[1:])
+			if err != nil {
+				fmt.Println(err)
+				fmt.Print("\n按回车键退出...")
+				fmt.Scanln()
+				return
+			}
+			if len(args) == 0 {
+				// 只有选项没有文件，进入交互模式
+				break
+			}
+
 			// 如果参数是文件路径，直接处理该文件
 			// 路径含空格时可能被拆成多个参数，需合并
-			filePath := strings.Join(os.Args[1:], " ")
+			filePath := strings.Join(args, " ")
 			if _, err := os.Stat(filePath); err != nil {
 				// 若合并后仍失败，尝试只用第一个参数（兼容正确传参的情况）
-				filePath = os.Args[1]
+				filePath = args[0]
 			}
 			if _, err := os.Stat(filePath); err == nil {
 				// 获取文件的绝对路径
@@ -74,18 +108,12 @@ func main() {
 					return
 				}
 
-				// 获取密码（从当前目录和程序所在目录查找）
-				passwords, passwordsInfo, err := getAllPasswords()
-				if err != nil {
-					fmt.Printf("\n提示：%v\n", err)
-					fmt.Println("将尝试空密码，如果失败可以手动输入密码")
-					passwords = []string{}
-					passwordsInfo = ""
-				}
-
 				// 处理文件
 				if getFileType(absPath) != -1 {
-					processArchive(absPath, passwords, passwordsInfo, nil)
+					// Ctrl+C 取消处理（终止 7z、保存破解进度），而不是直接退出留下 7z 进程
+					ctx, stop := withInterrupt(context.Background())
+					processArchive(ctx, absPath, nil)
+					stop()
 					// 处理更新和退出
 					handleUpdateAndExit()
 				} else {
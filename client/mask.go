@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// 掩码内置字符集（与 hashcat 一致）
+var maskBuiltinCharsets = map[rune]string{
+	'l': "abcdefghijklmnopqrstuvwxyz",
+	'u': "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	'd': "0123456789",
+	'h': "0123456789abcdef",
+	'H': "0123456789ABCDEF",
+	's': " !\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+}
+
+// maskCustomCharsets 自定义字符集的数量（?1 ~ ?4）
+const maskCustomCharsets = 4
+
+// maskSpec 掩码攻击参数
+type maskSpec struct {
+	Mask         string                     // 掩码，如 ?d?d?d?d、site?l?l?l2024
+	Charsets     [maskCustomCharsets]string // 自定义字符集 ?1 ~ ?4，如 ?l?d、abc123
+	Increment    bool                       // 按掩码长度逐位递增
+	IncrementMin int                        // 递增的最小长度（默认 1）
+	IncrementMax int                        // 递增的最大长度（默认为掩码长度）
+}
+
+// maskAttack 命令行指定的掩码攻击（Mask 为空表示使用字典）
+var maskAttack maskSpec
+
+// passwordMask 解析后的掩码，每个位置对应一个字符集
+type passwordMask struct {
+	positions [][]rune
+}
+
+// 函数说明：展开字符集定义（内置字符集 + 普通字符），按出现顺序去重
+// 参数：
+// def: 字符集定义，如 ?l?d、abc、?d-_
+// 返回：字符列表，错误信息
+func expandCharset(def string) ([]rune, error) {
+	var chars []rune
+	seen := make(map[rune]bool)
+	add := func(s string) {
+		for _, r := range s {
+			if !seen[r] {
+				seen[r] = true
+				chars = append(chars, r)
+			}
+		}
+	}
+
+	runes := []rune(def)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '?' {
+			add(string(runes[i]))
+			continue
+		}
+		if i+1 >= len(runes) {
+			return nil, fmt.Errorf("字符集 %q 末尾的 ? 缺少字符集名", def)
+		}
+		i++
+		switch name := runes[i]; {
+		case name == '?':
+			add("?")
+		case name == 'a':
+			add(maskBuiltinCharsets['l'] + maskBuiltinCharsets['u'] + maskBuiltinCharsets['d'] + maskBuiltinCharsets['s'])
+		case maskBuiltinCharsets[name] != "":
+			add(maskBuiltinCharsets[name])
+		default:
+			return nil, fmt.Errorf("字符集 %q 中有未知的字符集 ?%c", def, name)
+		}
+	}
+	return chars, nil
+}
+
+// 函数说明：解析掩码
+// ?l 小写字母、?u 大写字母、?d 数字、?s 符号、?a 以上全部、?h/?H 十六进制、?1~?4 自定义字符集、?? 问号，其他字符原样保留
+// 参数：
+// text: 掩码
+// charsets: 自定义字符集定义
+// 返回：掩码，错误信息
+func parseMask(text string, charsets [maskCustomCharsets]string) (passwordMask, error) {
+	var mask passwordMask
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '?' {
+			mask.positions = append(mask.positions, []rune{runes[i]})
+			continue
+		}
+		if i+1 >= len(runes) {
+			return mask, fmt.Errorf("掩码 %q 末尾的 ? 缺少字符集名", text)
+		}
+		i++
+		name := runes[i]
+		def := "?" + string(name)
+		if name >= '1' && name <= '0'+maskCustomCharsets {
+			def = charsets[name-'1']
+			if def == "" {
+				return mask, fmt.Errorf("掩码使用了 ?%c，但未指定自定义字符集 %c", name, name)
+			}
+		}
+		chars, err := expandCharset(def)
+		if err != nil {
+			return mask, err
+		}
+		if len(chars) == 0 {
+			return mask, fmt.Errorf("自定义字符集 %c 为空", name)
+		}
+		mask.positions = append(mask.positions, chars)
+	}
+	if len(mask.positions) == 0 {
+		return mask, fmt.Errorf("掩码为空")
+	}
+	return mask, nil
+}
+
+// keyspace 掩码能产生的密码数量，超出 int64 范围时返回 -1
+func (m passwordMask) keyspace() int64 {
+	space := int64(1)
+	for _, chars := range m.positions {
+		if space > math.MaxInt64/int64(len(chars)) {
+			return -1
+		}
+		space *= int64(len(chars))
+	}
+	return space
+}
+
+// candidate 第 index 个密码（最右边的位置变化最快）
+func (m passwordMask) candidate(index int64) string {
+	word := make([]rune, len(m.positions))
+	for i := len(m.positions) - 1; i >= 0; i-- {
+		chars := m.positions[i]
+		word[i] = chars[index%int64(len(chars))]
+		index /= int64(len(chars))
+	}
+	return string(word)
+}
+
+// prefix 掩码的前 n 个位置
+func (m passwordMask) prefix(n int) passwordMask {
+	return passwordMask{positions: m.positions[:n]}
+}
+
+// maskSource 按掩码逐个生成密码（递增模式下从短到长）
+type maskSource struct {
+	masks []passwordMask
+	space int64 // 全部掩码的密码总数
+	pos   int64
+}
+
+// 函数说明：由掩码参数创建候选来源
+// 参数：
+// spec: 掩码参数
+// 返回：候选来源，错误信息
+func newMaskSource(spec maskSpec) (*maskSource, error) {
+	full, err := parseMask(spec.Mask, spec.Charsets)
+	if err != nil {
+		return nil, err
+	}
+
+	masks := []passwordMask{full}
+	if spec.Increment {
+		minLen, maxLen := spec.IncrementMin, spec.IncrementMax
+		if minLen <= 0 {
+			minLen = 1
+		}
+		if maxLen <= 0 || maxLen > len(full.positions) {
+			maxLen = len(full.positions)
+		}
+		if minLen > maxLen {
+			return nil, fmt.Errorf("递增长度范围无效: %d-%d（掩码长度 %d）", minLen, maxLen, len(full.positions))
+		}
+		masks = masks[:0]
+		for n := minLen; n <= maxLen; n++ {
+			masks = append(masks, full.prefix(n))
+		}
+	}
+
+	s := &maskSource{masks: masks}
+	for _, m := range masks {
+		space := m.keyspace()
+		if space < 0 || s.space > math.MaxInt64-space {
+			return nil, fmt.Errorf("掩码 %q 的密码空间过大", spec.Mask)
+		}
+		s.space += space
+	}
+	return s, nil
+}
+
+func (s *maskSource) next() (string, bool) {
+	if s.pos >= s.space {
+		return "", false
+	}
+	s.pos++
+	return s.at(s.pos - 1), true
+}
+
+// at 第 index 个密码（index 须小于 total）
+func (s *maskSource) at(index int64) string {
+	for _, m := range s.masks {
+		space := m.keyspace()
+		if index < space {
+			return m.candidate(index)
+		}
+		index -= space
+	}
+	return ""
+}
+
+func (s *maskSource) total() int64 {
+	return s.space
+}
+
+func (s *maskSource) fingerprint() string {
+	h := sha256.New()
+	for _, m := range s.masks {
+		for _, chars := range m.positions {
+			h.Write([]byte(string(chars)))
+			h.Write([]byte{0})
+		}
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *maskSource) seek(pos int64) {
+	s.pos = min(pos, s.space)
+}
+
+// 函数说明：生成掩码攻击的说明信息（含密码空间）
+// 参数：
+// spec: 掩码参数
+// source: 由 spec 创建的候选来源
+// 返回：说明信息
+func formatMaskInfo(spec maskSpec, source *maskSource) string {
+	return fmt.Sprintf("掩码: %s\n密码空间: 共 %d 个密码", describeMask(spec, source), source.total())
+}
+
+// describeMask 掩码及其自定义字符集、递增范围的简短描述
+func describeMask(spec maskSpec, source *maskSource) string {
+	desc := spec.Mask
+	for i, def := range spec.Charsets {
+		if def != "" {
+			desc += fmt.Sprintf("  ?%d=%s", i+1, def)
+		}
+	}
+	if len(source.masks) > 1 {
+		desc += fmt.Sprintf("（长度 %d-%d 逐位递增）", len(source.masks[0].positions), len(source.masks[len(source.masks)-1].positions))
+	}
+	return desc
+}
+
+// 函数说明：交互式输入掩码参数
+// 参数：
+// reader: 输入读取器
+// 返回：掩码参数，是否输入了掩码
+func readMaskSpec(reader *bufio.Reader) (maskSpec, bool) {
+	var spec maskSpec
+	fmt.Println("\n掩码中 ?l 小写字母、?u 大写字母、?d 数字、?s 符号、?a 全部、?1~?4 自定义字符集，其他字符原样保留")
+	fmt.Print("请输入掩码，如 ?d?d?d?d?d?d（直接回车取消）: ")
+	spec.Mask = readLineInput(reader)
+	if spec.Mask == "" {
+		return spec, false
+	}
+
+	for i := range spec.Charsets {
+		if strings.Contains(spec.Mask, fmt.Sprintf("?%d", i+1)) {
+			fmt.Printf("请输入自定义字符集 ?%d，如 ?l?d 或 abc123: ", i+1)
+			spec.Charsets[i] = readLineInput(reader)
+		}
+	}
+
+	fmt.Print("是否从 1 位开始逐位递增长度? (y/n) [N]: ")
+	answer := strings.ToLower(readLineInput(reader))
+	spec.Increment = answer == "y" || answer == "yes"
+	return spec, true
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMask(t *testing.T) {
+	tests := []struct {
+		mask     string
+		charsets [maskCustomCharsets]string
+		want     []string // 每个位置的字符集
+		err      bool
+	}{
+		{mask: "?d", want: []string{"0123456789"}},
+		{mask: "a?l", want: []string{"a", maskBuiltinCharsets['l']}},
+		{mask: "?h?H", want: []string{"0123456789abcdef", "0123456789ABCDEF"}},
+		{mask: "??", want: []string{"?"}},
+		{mask: "密?d", want: []string{"密", "0123456789"}},
+		{mask: "?1", charsets: [maskCustomCharsets]string{"?dab"}, want: []string{"0123456789ab"}},
+		{mask: "?2", charsets: [maskCustomCharsets]string{"", "aab?d1"}, want: []string{"ab0123456789"}}, // 按出现顺序去重
+		{mask: "?4", charsets: [maskCustomCharsets]string{3: "-_??"}, want: []string{"-_?"}},
+		{mask: "", err: true},
+		{mask: "abc?", err: true},
+		{mask: "?x", err: true},
+		{mask: "?1", err: true}, // 未指定自定义字符集
+		{mask: "?1", charsets: [maskCustomCharsets]string{"?"}, err: true},
+		{mask: "?1", charsets: [maskCustomCharsets]string{"?z"}, err: true},
+	}
+	for _, tt := range tests {
+		mask, err := parseMask(tt.mask, tt.charsets)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: 应返回错误", tt.mask)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.mask, err)
+			continue
+		}
+		var got []string
+		for _, chars := range mask.positions {
+			got = append(got, string(chars))
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%q: 解析为 %q，应为 %q", tt.mask, got, tt.want)
+		}
+	}
+
+	if mask, err := parseMask("?a", [maskCustomCharsets]string{}); err != nil || len(mask.positions[0]) != 95 {
+		t.Errorf("?a 应为 95 个可见 ASCII 字符")
+	}
+}
+
+func TestMaskSourceKeyspace(t *testing.T) {
+	tests := []struct {
+		spec  maskSpec
+		total int64
+		first []string
+		last  string
+		err   bool
+	}{
+		{spec: maskSpec{Mask: "?d?d"}, total: 100, first: []string{"00", "01", "02"}, last: "99"},
+		{spec: maskSpec{Mask: "ab?d"}, total: 10, first: []string{"ab0", "ab1"}, last: "ab9"},
+		{spec: maskSpec{Mask: "?l?d"}, total: 260, first: []string{"a0", "a1"}, last: "z9"},
+		{spec: maskSpec{Mask: "?d?d?d", Increment: true}, total: 10 + 100 + 1000, first: []string{"0", "1"}, last: "999"},
+		{spec: maskSpec{Mask: "?d?d?d", Increment: true, IncrementMin: 2}, total: 100 + 1000, first: []string{"00", "01"}, last: "999"},
+		{spec: maskSpec{Mask: "?d?d?d", Increment: true, IncrementMax: 2}, total: 10 + 100, first: []string{"0"}, last: "99"},
+		{spec: maskSpec{Mask: "?d?d", Increment: true, IncrementMax: 5}, total: 10 + 100, last: "99"}, // 最大长度不超过掩码长度
+		{spec: maskSpec{Mask: "?d?d", Increment: true, IncrementMin: 3}, err: true},
+		{spec: maskSpec{Mask: strings.Repeat("?a", 9)}, total: 630249409724609375, last: strings.Repeat("~", 9)}, // 95^9
+		{spec: maskSpec{Mask: strings.Repeat("?a", 9), Increment: true}, total: 636954190679126495, last: strings.Repeat("~", 9)},
+		{spec: maskSpec{Mask: strings.Repeat("?a", 10)}, err: true}, // 超出 int64
+		// 单个掩码不超出 int64，递增的总数超出
+		{spec: maskSpec{Mask: strings.Repeat("?d", 18) + "?1", Charsets: [maskCustomCharsets]string{"abcdefghi"}}, total: 9e18, last: strings.Repeat("9", 18) + "i"},
+		{spec: maskSpec{Mask: strings.Repeat("?d", 18) + "?1", Charsets: [maskCustomCharsets]string{"abcdefghi"}, Increment: true}, err: true},
+	}
+	for _, tt := range tests {
+		source, err := newMaskSource(tt.spec)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%+v: 应返回错误", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%+v: %v", tt.spec, err)
+			continue
+		}
+		if source.total() != tt.total {
+			t.Errorf("%+v: 密码空间为 %d，应为 %d", tt.spec, source.total(), tt.total)
+		}
+		for i, want := range tt.first {
+			if got := source.at(int64(i)); got != want {
+				t.Errorf("%+v: 第 %d 个为 %q，应为 %q", tt.spec, i, got, want)
+			}
+		}
+		if got := source.at(source.total() - 1); got != tt.last {
+			t.Errorf("%+v: 最后一个为 %q，应为 %q", tt.spec, got, tt.last)
+		}
+	}
+}
+
+// 从任意位置继续时，剩下的密码与从头生成时的对应部分完全一致
+func TestMaskSourceSeek(t *testing.T) {
+	spec := maskSpec{Mask: "?d?1", Charsets: [maskCustomCharsets]string{"xyz"}, Increment: true}
+	source, err := newMaskSource(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := drainCandidates(source)
+	if int64(len(all)) != source.total() || source.total() != 10+30 {
+		t.Fatalf("生成了 %d 个密码，密码空间为 %d", len(all), source.total())
+	}
+	for _, pos := range []int64{0, 1, 9, 10, 11, 39, 40, 100} {
+		source, _ := newMaskSource(spec)
+		source.seek(pos)
+		got := drainCandidates(source)
+		want := all[min(pos, int64(len(all))):]
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("从 %d 继续得到 %q，应为 %q", pos, got, want)
+		}
+	}
+}
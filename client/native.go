@@ -0,0 +1,690 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// nativeMaxLinkLen 符号链接目标的长度上限（zip 中链接目标保存为文件内容）
+const nativeMaxLinkLen = 4096
+
+// errNativeUnsupported 纯 Go 后端无法处理，交给 7z
+var errNativeUnsupported = errors.New("纯 Go 后端不支持该压缩包")
+
+// NATIVE_TAR_SUFFIXES 压缩过的 tar 包，解压时直接解出其中的文件
+var NATIVE_TAR_SUFFIXES = []string{".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tbz", ".tar.xz", ".txz"}
+
+// nativeMagics 各压缩格式的文件头，不匹配（扩展名与实际格式不符）时交给 7z 按内容识别
+var nativeMagics = map[int][]byte{
+	TYPE_GZ:  {0x1f, 0x8b},
+	TYPE_BZ2: []byte("BZh"),
+	TYPE_XZ:  xzMagic,
+}
+
+// nativeBackend 用 Go 标准库（xz 为 xz.go）处理无需密码的 tar/gz/bz2/xz 和未加密的 zip，不启动 7z 进程
+// 遇到不支持的情况返回 RESULT_UNSUPPORTED，由 fallbackBackend 改用 7z
+type nativeBackend struct{}
+
+// nativeEntry 压缩包中的一项
+type nativeEntry struct {
+	Name     string // 以 / 分隔的相对路径
+	Size     int64
+	Mode     os.FileMode
+	ModTime  time.Time
+	IsDir    bool
+	Link     string // 链接目标（非空时为链接）
+	HardLink bool   // Link 是包内另一个文件（tar 硬链接）
+}
+
+// 函数说明：判断压缩包能否由纯 Go 后端处理
+// 参数：
+// archivePath: 压缩文件路径
+// 返回：是否可以
+func canExtractNatively(archivePath string) bool {
+	switch getFileType(archivePath) {
+	case TYPE_TAR, TYPE_GZ, TYPE_BZ2, TYPE_XZ:
+		return true
+	case TYPE_ZIP:
+		// 只处理没有加密、压缩方式为存储或 Deflate 的 zip
+		r, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return false
+		}
+		defer r.Close()
+		return checkPlainZip(r.File) == nil
+	}
+	return false
+}
+
+// checkPlainZip 检查 zip 中每个文件都未加密且压缩方式受支持（在写出任何文件前检查，避免解压到一半改用 7z）
+func checkPlainZip(files []*zip.File) error {
+	for _, f := range files {
+		if f.Flags&0x1 != 0 {
+			return errNativeUnsupported
+		}
+		if f.Method != zip.Store && f.Method != zip.Deflate {
+			return errNativeUnsupported
+		}
+	}
+	return nil
+}
+
+// isCompressedTar 是否为压缩过的 tar 包（按扩展名判断）
+func isCompressedTar(archivePath string) bool {
+	lower := strings.ToLower(archivePath)
+	for _, suffix := range NATIVE_TAR_SUFFIXES {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ctxReader 读取前检查上下文，取消后停止解压；onRead 非空时回调读取的字节数（用于进度）
+type ctxReader struct {
+	ctx    context.Context
+	r      io.Reader
+	onRead func(n int)
+}
+
+func (c ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := c.r.Read(p)
+	if c.onRead != nil {
+		c.onRead(n)
+	}
+	return n, err
+}
+
+// ctxReaderAt zip 使用的随机读取，与 ctxReader 相同
+type ctxReaderAt struct {
+	ctx    context.Context
+	r      io.ReaderAt
+	onRead func(n int)
+}
+
+func (c ctxReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := c.r.ReadAt(p, off)
+	if c.onRead != nil {
+		c.onRead(n)
+	}
+	return n, err
+}
+
+// 函数说明：逐项遍历压缩包
+// 参数：
+// ctx: 上下文（取消后停止）
+// archivePath: 压缩文件路径
+// onRead: 读取压缩包时回调读取的字节数（xz 在后台协程中回调），可以为空
+// fn: 对每一项调用，r 为该项的数据（可以不读）
+// 返回：错误信息（不支持时为 errNativeUnsupported）
+func walkNativeArchive(ctx context.Context, archivePath string, onRead func(n int), fn func(entry nativeEntry, r io.Reader) error) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return &archiveError{Result: RESULT_FAILED, Detail: err.Error()}
+	}
+	defer file.Close()
+
+	fileType := getFileType(archivePath)
+	if fileType == TYPE_ZIP {
+		return walkZip(ctx, file, onRead, fn)
+	}
+	br := bufio.NewReaderSize(ctxReader{ctx: ctx, r: file, onRead: onRead}, 64*1024)
+
+	var stream io.Reader
+	switch fileType {
+	case TYPE_TAR:
+		return walkTar(br, fn)
+	case TYPE_GZ, TYPE_BZ2, TYPE_XZ:
+		magic := nativeMagics[fileType]
+		if head, _ := br.Peek(len(magic)); !bytes.Equal(head, magic) {
+			return errNativeUnsupported
+		}
+		switch fileType {
+		case TYPE_GZ:
+			gz, err := gzip.NewReader(br)
+			if err != nil {
+				return err
+			}
+			defer gz.Close()
+			stream = gz
+		case TYPE_BZ2:
+			stream = bzip2.NewReader(br)
+		case TYPE_XZ:
+			xz := newXZReader(br)
+			defer xz.Close()
+			stream = xz
+		}
+	default:
+		return errNativeUnsupported
+	}
+
+	if isCompressedTar(archivePath) {
+		return walkTar(stream, fn)
+	}
+
+	// 单个压缩文件：解出的文件名为去掉扩展名的压缩包名（与 7z 一致）
+	name := filepath.Base(archivePath)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	info, err := file.Stat()
+	if err != nil {
+		return &archiveError{Result: RESULT_FAILED, Detail: err.Error()}
+	}
+	entry := nativeEntry{Name: name, Size: -1, Mode: 0644, ModTime: info.ModTime()}
+	if err := fn(entry, stream); err != nil {
+		return err
+	}
+	// 读完剩余数据以完成校验
+	_, err = io.Copy(io.Discard, stream)
+	return err
+}
+
+// walkTar 遍历 tar 包
+func walkTar(r io.Reader, fn func(entry nativeEntry, r io.Reader) error) error {
+	tr := tar.NewReader(r)
+	for first := true; ; first = false {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if first && errors.Is(err, tar.ErrHeader) {
+				// 第一个文件头就无法解析：可能不是 tar，交给 7z 按内容识别
+				return errNativeUnsupported
+			}
+			return err
+		}
+		entry := nativeEntry{
+			Name:    header.Name,
+			Size:    header.Size,
+			Mode:    header.FileInfo().Mode(),
+			ModTime: header.ModTime,
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			entry.IsDir = true
+		case tar.TypeSymlink:
+			entry.Link = header.Linkname
+		case tar.TypeLink:
+			entry.Link = header.Linkname
+			entry.HardLink = true
+		case tar.TypeReg, tar.TypeGNUSparse:
+			// 普通文件
+		default:
+			// 设备文件、FIFO 等跳过（PAX 扩展头、GNU 长文件名已由 archive/tar 处理）
+			continue
+		}
+		if err := fn(entry, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// walkZip 遍历未加密的 zip 包（GBK 文件名自动转换）
+func walkZip(ctx context.Context, file *os.File, onRead func(n int), fn func(entry nativeEntry, r io.Reader) error) error {
+	info, err := file.Stat()
+	if err != nil {
+		return &archiveError{Result: RESULT_FAILED, Detail: err.Error()}
+	}
+	zr, err := zip.NewReader(ctxReaderAt{ctx: ctx, r: file, onRead: onRead}, info.Size())
+	if err != nil {
+		return errNativeUnsupported
+	}
+	if err := checkPlainZip(zr.File); err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		name := f.Name
+		if f.NonUTF8 {
+			name = decodeGBK(name)
+		}
+		entry := nativeEntry{
+			Name:    name,
+			Size:    int64(f.UncompressedSize64),
+			Mode:    f.Mode(),
+			ModTime: f.Modified,
+			IsDir:   strings.HasSuffix(name, "/") || f.FileInfo().IsDir(),
+		}
+		if entry.IsDir {
+			if err := fn(entry, bytes.NewReader(nil)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		r := io.Reader(rc)
+		if entry.Mode&os.ModeSymlink != 0 {
+			// zip 中符号链接的目标保存为文件内容
+			target, err := io.ReadAll(io.LimitReader(r, nativeMaxLinkLen))
+			if err != nil {
+				rc.Close()
+				return err
+			}
+			entry.Link = string(target)
+			r = bytes.NewReader(nil)
+		}
+		err = fn(entry, r)
+		if err == nil {
+			// 读完剩余数据以完成 CRC 校验
+			_, err = io.Copy(io.Discard, r)
+		}
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 函数说明：把纯 Go 后端的错误归类为结果类型
+// 参数：
+// err: 错误
+// 返回：结果类型
+func nativeResult(err error) SevenZipResult {
+	var archiveErr *archiveError
+	switch {
+	case err == nil:
+		return RESULT_OK
+	case errors.As(err, &archiveErr):
+		return archiveErr.Result
+	case errors.Is(err, errNativeUnsupported), errors.Is(err, errXZUnsupported), errors.Is(err, zip.ErrAlgorithm):
+		return RESULT_UNSUPPORTED
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return RESULT_UNKNOWN
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return RESULT_MISSING_VOLUME
+	}
+	// 校验和错误、格式错误等
+	return RESULT_CORRUPT_DATA
+}
+
+// nativeError 把错误转换为 *archiveError
+func nativeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var archiveErr *archiveError
+	if errors.As(err, &archiveErr) {
+		return archiveErr
+	}
+	return &archiveError{Result: nativeResult(err), Detail: err.Error()}
+}
+
+// Test 读取全部数据完成校验（gzip/bzip2/xz 与 zip 自带校验和；无需密码，password 被忽略）
+func (nativeBackend) Test(ctx context.Context, archivePath, password, entry string) SevenZipResult {
+	err := walkNativeArchive(ctx, archivePath, nil, func(e nativeEntry, r io.Reader) error {
+		if entry != "" && e.Name != entry {
+			return nil
+		}
+		_, err := io.Copy(io.Discard, r)
+		return err
+	})
+	return nativeResult(err)
+}
+
+// List 列出压缩包内的文件（单个压缩文件的大小未知，为 -1）
+func (nativeBackend) List(ctx context.Context, archivePath, password string) ([]archiveEntry, SevenZipResult) {
+	var entries []archiveEntry
+	err := walkNativeArchive(ctx, archivePath, nil, func(e nativeEntry, r io.Reader) error {
+		entries = append(entries, archiveEntry{
+			Path:  filepath.FromSlash(strings.TrimSuffix(e.Name, "/")),
+			Size:  e.Size,
+			IsDir: e.IsDir,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, nativeResult(err)
+	}
+	return entries, RESULT_OK
+}
+
+// Extract 解压到 extractPath（保留目录结构，覆盖同名文件）
+// 进度按已读取的压缩包字节数计算，解压后的总大小未知
+func (nativeBackend) Extract(ctx context.Context, archivePath, password, extractPath string, onProgress ProgressFunc) error {
+	var progress *nativeProgress
+	var onRead func(n int)
+	if onProgress != nil {
+		progress = &nativeProgress{throttle: newProgressThrottle(onProgress)}
+		if info, err := os.Stat(archivePath); err == nil {
+			progress.size = info.Size()
+		}
+		onRead = progress.onRead
+	}
+
+	extractor := newNativeExtractor(extractPath)
+	err := walkNativeArchive(ctx, archivePath, onRead, func(entry nativeEntry, r io.Reader) error {
+		if progress != nil {
+			progress.progress.File = entry.Name
+			progress.progress.Files++
+			r = &nativeProgressReader{r: r, p: progress}
+		}
+		return extractor.extract(entry, r)
+	})
+	if err == nil {
+		extractor.createLinks()
+	}
+	if err == nil && progress != nil {
+		progress.progress.Percent = 100
+		progress.throttle.report(progress.progress, true)
+	}
+	return nativeError(err)
+}
+
+// nativeProgress 纯 Go 后端的解压进度
+type nativeProgress struct {
+	throttle *progressThrottle
+	size     int64        // 压缩包大小
+	consumed atomic.Int64 // 已读取的压缩包字节数
+	progress ExtractProgress
+}
+
+func (p *nativeProgress) onRead(n int) {
+	p.consumed.Add(int64(n))
+}
+
+// nativeProgressReader 统计解出的字节数并回调进度
+type nativeProgressReader struct {
+	r io.Reader
+	p *nativeProgress
+}
+
+func (r *nativeProgressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	p := r.p
+	p.progress.Bytes += int64(n)
+	if p.size > 0 {
+		p.progress.Percent = int(min(p.consumed.Load()*100/p.size, 99))
+	}
+	p.throttle.report(p.progress, false)
+	return n, err
+}
+
+// Version 纯 Go 后端的名称
+func (nativeBackend) Version() string {
+	return fmt.Sprintf("纯 Go（%s）", runtime.Version())
+}
+
+// 函数说明：计算压缩包中一项的解压位置，拒绝绝对路径和跳出解压目录的路径
+// 参数：
+// extractPath: 解压目录
+// name: 包内路径
+// 返回：解压位置，错误信息
+func safeExtractPath(extractPath, name string) (string, error) {
+	// 去掉开头的 / 和 . 部分，含有 .. 的路径直接拒绝
+	var parts []string
+	for _, part := range strings.Split(strings.ReplaceAll(name, "\\", "/"), "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			return "", fmt.Errorf("压缩包中有不安全的路径: %q", name)
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("无效的文件名: %q", name)
+	}
+	rel := strings.Join(parts, "/")
+	if runtime.GOOS == "windows" {
+		rel = sanitizeWindowsName(rel)
+	}
+	return filepath.Join(extractPath, filepath.FromSlash(rel)), nil
+}
+
+// sanitizeWindowsName 把 Windows 文件名中不允许的字符替换为 _（与 7z 一致）
+func sanitizeWindowsName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// nativeExtractor 一次解压的状态
+// 链接在所有文件写完后才创建，之后不再写入任何文件，避免借包内先创建的链接写到解压目录外
+type nativeExtractor struct {
+	extractPath string
+	files       map[string]bool // 本次解出的普通文件，硬链接只能指向这些文件
+	links       []nativeLink    // 待创建的链接（按包内顺序）
+}
+
+// nativeLink 待创建的链接
+type nativeLink struct {
+	target string
+	entry  nativeEntry
+}
+
+func newNativeExtractor(extractPath string) *nativeExtractor {
+	return &nativeExtractor{extractPath: extractPath, files: make(map[string]bool)}
+}
+
+// 函数说明：检查从解压目录到 path 的每一级都是真实目录（不是链接），拒绝经由链接写到解压目录外
+// 参数：
+// path: 解压目录内的路径（不含解压目录本身）
+// 返回：错误信息
+func (x *nativeExtractor) checkRealDirs(path string) error {
+	rel, err := filepath.Rel(x.extractPath, path)
+	if err != nil {
+		return err
+	}
+	dir := x.extractPath
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("压缩包中有不安全的路径（经由链接或文件）: %s", path)
+		}
+	}
+	return nil
+}
+
+// 函数说明：解压一项（链接先记下，由 createLinks 最后创建）
+// 参数：
+// entry: 包内的一项
+// r: 该项的数据
+// 返回：错误信息（写入失败时为 RESULT_FAILED 的 *archiveError）
+func (x *nativeExtractor) extract(entry nativeEntry, r io.Reader) error {
+	if entry.IsDir && strings.Trim(entry.Name, "./") == "" {
+		// tar 中代表根目录的 "./"
+		return nil
+	}
+	target, err := safeExtractPath(x.extractPath, entry.Name)
+	if err != nil {
+		return &archiveError{Result: RESULT_FAILED, Detail: err.Error()}
+	}
+	writeFailed := func(err error) error {
+		return &archiveError{Result: RESULT_FAILED, Detail: fmt.Sprintf("写入 %s 失败: %v", target, err)}
+	}
+
+	if entry.Link != "" {
+		x.links = append(x.links, nativeLink{target: target, entry: entry})
+		return nil
+	}
+
+	dir := target
+	if !entry.IsDir {
+		dir = filepath.Dir(target)
+	}
+	if err := x.checkRealDirs(dir); err != nil {
+		return &archiveError{Result: RESULT_FAILED, Detail: err.Error()}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return writeFailed(err)
+	}
+	if entry.IsDir {
+		return nil
+	}
+
+	// 已有同名链接时先删除，不写到链接指向的文件
+	if info, err := os.Lstat(target); err == nil && !info.Mode().IsRegular() && !info.IsDir() {
+		if err := os.Remove(target); err != nil {
+			return writeFailed(err)
+		}
+	}
+	perm := entry.Mode.Perm()
+	if perm == 0 {
+		perm = 0644
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm|0200)
+	if err != nil {
+		return writeFailed(err)
+	}
+	x.files[target] = true
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			return writeFailed(err)
+		}
+		// 读取（解压、校验）失败
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return writeFailed(err)
+	}
+	if !entry.ModTime.IsZero() {
+		os.Chtimes(target, entry.ModTime, entry.ModTime)
+	}
+	return nil
+}
+
+// createLinks 创建全部链接：目标必须在解压目录内，否则跳过（与 7z 默认行为一致）；
+// 系统不支持（如 Windows 未开启开发者模式）时也跳过
+func (x *nativeExtractor) createLinks() {
+	var symlinks []string
+	for _, link := range x.links {
+		target, entry := link.target, link.entry
+		if x.checkRealDirs(filepath.Dir(target)) != nil || os.MkdirAll(filepath.Dir(target), 0755) != nil {
+			continue
+		}
+
+		if entry.HardLink {
+			// 只链接本次解出、仍是普通文件的文件
+			source, err := safeExtractPath(x.extractPath, entry.Link)
+			if err != nil || !x.files[source] || x.checkRealDirs(filepath.Dir(source)) != nil {
+				continue
+			}
+			if info, err := os.Lstat(source); err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			os.Remove(target)
+			if os.Link(source, target) != nil {
+				// 不支持硬链接的文件系统：复制一份
+				copyFile(source, target)
+			}
+			continue
+		}
+
+		linkTarget := filepath.FromSlash(entry.Link)
+		if filepath.IsAbs(linkTarget) || !isWithinDir(x.extractPath, filepath.Join(filepath.Dir(target), linkTarget)) {
+			continue
+		}
+		os.Remove(target)
+		if os.Symlink(linkTarget, target) == nil {
+			symlinks = append(symlinks, target)
+		}
+	}
+
+	// 链接可能经由其他链接（如指向 . 的链接再加 ..）指到解压目录外，全部创建后按实际指向再检查一遍
+	root, err := filepath.EvalSymlinks(x.extractPath)
+	if err != nil {
+		return
+	}
+	for _, link := range symlinks {
+		if resolved, err := filepath.EvalSymlinks(link); err == nil && !isWithinDir(root, resolved) {
+			os.Remove(link)
+		}
+	}
+}
+
+// isWithinDir path 是否为 dir 或其中的路径（按字符串比较，不解析链接）
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// copyFile 复制文件
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// fallbackBackend 先用 primary 处理，结果为 RESULT_UNSUPPORTED 时改用 fallback
+type fallbackBackend struct {
+	primary  ArchiveBackend
+	fallback ArchiveBackend
+}
+
+func (b *fallbackBackend) Test(ctx context.Context, archivePath, password, entry string) SevenZipResult {
+	if result := b.primary.Test(ctx, archivePath, password, entry); result != RESULT_UNSUPPORTED {
+		return result
+	}
+	return b.fallback.Test(ctx, archivePath, password, entry)
+}
+
+func (b *fallbackBackend) List(ctx context.Context, archivePath, password string) ([]archiveEntry, SevenZipResult) {
+	if entries, result := b.primary.List(ctx, archivePath, password); result != RESULT_UNSUPPORTED {
+		return entries, result
+	}
+	return b.fallback.List(ctx, archivePath, password)
+}
+
+func (b *fallbackBackend) Extract(ctx context.Context, archivePath, password, extractPath string, onProgress ProgressFunc) error {
+	err := b.primary.Extract(ctx, archivePath, password, extractPath, onProgress)
+	var archiveErr *archiveError
+	if errors.As(err, &archiveErr) && archiveErr.Result == RESULT_UNSUPPORTED {
+		return b.fallback.Extract(ctx, archivePath, password, extractPath, onProgress)
+	}
+	return err
+}
+
+func (b *fallbackBackend) Version() string {
+	return fmt.Sprintf("%s，不支持时使用 %s", b.primary.Version(), b.fallback.Version())
+}
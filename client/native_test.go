@@ -0,0 +1,153 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// tarItem 测试用 tar 包中的一项
+type tarItem struct {
+	name     string
+	typeflag byte
+	link     string
+	body     string
+}
+
+// writeTestTar 按顺序写出 tar 包
+func writeTestTar(t *testing.T, path string, items []tarItem) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	for _, item := range items {
+		header := &tar.Header{Name: item.name, Typeflag: item.typeflag, Linkname: item.link, Mode: 0644}
+		if item.typeflag == tar.TypeReg {
+			header.Size = int64(len(item.body))
+		}
+		if item.typeflag == tar.TypeDir {
+			header.Mode = 0755
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if item.typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(item.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNativeExtractSymlinkChain(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "slip.tar")
+	writeTestTar(t, archive, []tarItem{
+		{name: "d/e/", typeflag: tar.TypeDir},
+		{name: "d/e/x", typeflag: tar.TypeSymlink, link: "../.."},
+		{name: "d/e/x/y", typeflag: tar.TypeSymlink, link: "../.."},
+		{name: "d/e/x/y/ESCAPED.txt", typeflag: tar.TypeReg, body: "escaped"},
+	})
+	out := filepath.Join(dir, "out", "inner")
+	if err := os.MkdirAll(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	nativeBackend{}.Extract(context.Background(), archive, "", out, nil)
+
+	for _, path := range []string{
+		filepath.Join(dir, "ESCAPED.txt"),
+		filepath.Join(dir, "out", "ESCAPED.txt"),
+	} {
+		if _, err := os.Lstat(path); err == nil {
+			t.Errorf("文件被写到解压目录外: %s", path)
+		}
+	}
+}
+
+func TestNativeExtractHardlinkEscape(t *testing.T) {
+	dir := t.TempDir()
+	victim := filepath.Join(dir, "victim.txt")
+	if err := os.WriteFile(victim, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out", "inner")
+	if err := os.MkdirAll(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// 解压前已在解压目录中、不是本次解出的文件
+	existing := filepath.Join(out, "existing.txt")
+	if err := os.WriteFile(existing, []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := filepath.Join(dir, "hard.tar")
+	writeTestTar(t, archive, []tarItem{
+		{name: "x", typeflag: tar.TypeSymlink, link: "."},
+		{name: "y", typeflag: tar.TypeSymlink, link: "x/.."},
+		{name: "h", typeflag: tar.TypeLink, link: "y/victim.txt"},
+		{name: "h", typeflag: tar.TypeReg, body: "overwritten"},
+		{name: "d/e/", typeflag: tar.TypeDir},
+		{name: "d/e/c", typeflag: tar.TypeSymlink, link: "../.."},
+		{name: "d/e/c/f", typeflag: tar.TypeSymlink, link: "../.."},
+		{name: "chain", typeflag: tar.TypeLink, link: "d/e/c/f/victim.txt"},
+		{name: "chain", typeflag: tar.TypeReg, body: "overwritten"},
+		{name: "e", typeflag: tar.TypeLink, link: "existing.txt"},
+		{name: "e", typeflag: tar.TypeReg, body: "overwritten"},
+	})
+
+	nativeBackend{}.Extract(context.Background(), archive, "", out, nil)
+
+	for path, want := range map[string]string{victim: "original", existing: "existing"} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%s 被改写为 %q", path, data)
+		}
+	}
+	if resolved, err := filepath.EvalSymlinks(filepath.Join(out, "y")); err == nil {
+		if root, _ := filepath.EvalSymlinks(out); !isWithinDir(root, resolved) {
+			t.Errorf("保留了指向解压目录外的链接: y -> %s", resolved)
+		}
+	}
+}
+
+func TestNativeExtractLinksInside(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "links.tar")
+	writeTestTar(t, archive, []tarItem{
+		{name: "sub/", typeflag: tar.TypeDir},
+		{name: "sub/a.txt", typeflag: tar.TypeReg, body: "hello"},
+		{name: "sub/hard.txt", typeflag: tar.TypeLink, link: "sub/a.txt"},
+		{name: "soft.txt", typeflag: tar.TypeSymlink, link: "sub/a.txt"},
+	})
+	out := filepath.Join(dir, "out")
+
+	if err := (nativeBackend{}).Extract(context.Background(), archive, "", out, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(out, "sub", "hard.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Errorf("硬链接: %q, %v", data, err)
+	}
+	if runtime.GOOS == "windows" {
+		// 未开启开发者模式时无法创建符号链接
+		return
+	}
+	data, err = os.ReadFile(filepath.Join(out, "soft.txt"))
+	if err != nil || string(data) != "hello" {
+		t.Errorf("符号链接: %q, %v", data, err)
+	}
+}
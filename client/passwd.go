@@ -2,210 +2,487 @@ package main
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
-	"time"
 )
 
-// 定义文件大小阈值(10MB)
-const FILE_SIZE_THRESHOLD = 10 * 1024 * 1024
+// passwordScanBufferSize 读取密码文件时单行的最大长度
+const passwordScanBufferSize = 512 * 1024 // 512KB
 
-// 函数说明：读取密码文件
+// passwordDict 由一个或多个密码文件组成的字典
+// 密码不放进内存：统计时和每次使用时都从文件流式读取，先用布隆过滤器找出可能重复的密码，
+// 只为这些密码占用内存精确去重，因此可以直接使用数 GB 的大字典，且不会漏掉任何密码
+type passwordDict struct {
+	paths       []string         // 按顺序使用的密码文件
+	fileCounts  map[string]int64 // 各文件的密码数量（去重前）
+	suspects    map[string]bool  // 可能重复的密码，nil 时不去重（可能重复的密码过多）
+	hot         []string         // 曾经成功过的密码，按成功次数排序，最先尝试
+	hotSet      map[string]bool  // hot 中的密码，按文件顺序读取其余密码时跳过
+	count       int64            // 去重后的密码数量
+	fingerprint string           // 去重后全部密码按文件顺序的 SHA-256（与成功记录无关）
+}
+
+// newPasswordScanner 创建逐行读取密码的扫描器
+func newPasswordScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	// 注意：设置更大的 buffer 以提高读取性能
+	scanner.Buffer(make([]byte, passwordScanBufferSize), passwordScanBufferSize)
+	return scanner
+}
+
+// 函数说明：加载字典：流式读两遍所有密码文件，第一遍找出可能重复的密码，
+// 第二遍统计数量、找出曾经成功过的密码并计算指纹
 // 参数：
-// path: 密码文件路径
-// 返回：密码列表，密码数量，错误信息
-func readPasswordFile(path string) ([]string, int, error) {
-	// 获取文件信息和大小
-	fileInfo, err := os.Stat(path)
-	if err != nil {
-		return nil, 0, fmt.Errorf("获取文件信息失败: %v", err)
+// paths: 密码文件路径（按优先级排列）
+// 返回：字典（不存在或为空的文件不计入），错误信息
+func loadPasswordDict(paths []string) (*passwordDict, error) {
+	dict := &passwordDict{fileCounts: make(map[string]int64)}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			dict.paths = append(dict.paths, path)
+		}
 	}
 
-	// 小文件直接读取
-	if fileInfo.Size() <= FILE_SIZE_THRESHOLD {
-		passwords, err := scanPasswords(path)
+	suspects, err := findDuplicateSuspects(dict.paths, dedupMaxSuspects)
+	if err == errTooManySuspects {
+		fmt.Printf("字典中可能重复的密码超过 %d 个，去重需要的内存过多，本次不去重（重复的密码会多测试几次，不会漏掉）\n", dedupMaxSuspects)
+	} else if err != nil {
+		return nil, err
+	}
+	dict.suspects = suspects
+
+	hits := loadPasswordHits()
+	dedup := newDedupSet(dict.suspects)
+	dict.hotSet = make(map[string]bool)
+	h := sha256.New()
+	var usedPaths []string
+	for _, path := range dict.paths {
+		file, err := openPasswordFile(path)
 		if err != nil {
-			return nil, 0, err
+			return nil, fmt.Errorf("打开密码文件失败: %v", err)
+		}
+		var fileCount int64
+		scanner := newPasswordScanner(file)
+		for scanner.Scan() {
+			password := strings.TrimSpace(scanner.Text())
+			if password == "" {
+				continue
+			}
+			fileCount++
+			if dedup.duplicate(password) || dict.hotSet[password] {
+				continue
+			}
+			dict.count++
+			h.Write([]byte(password))
+			h.Write([]byte{'\n'})
+			if hits[password].Count > 0 {
+				dict.hotSet[password] = true
+				dict.hot = append(dict.hot, password)
+			}
+		}
+		err = scanner.Err()
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取密码文件 %s 失败: %v", path, err)
+		}
+		if fileCount > 0 {
+			usedPaths = append(usedPaths, path)
+			dict.fileCounts[path] = fileCount
 		}
-		return passwords, len(passwords), nil
 	}
+	dict.paths = usedPaths
 
-	// 大文件用 goroutine 读取并加超时，避免慢盘长时间阻塞
-	resultChan := make(chan []string, 1)
-	errorChan := make(chan error, 1)
-	go func() {
-		passwords, err := scanPasswords(path)
-		if err != nil {
-			errorChan <- err
-			return
+	// 曾经成功过的密码优先；这部分的顺序随成功记录变化，不计入指纹，续跑时由检查点恢复
+	sortPasswordsByHits(dict.hot, hits)
+	dict.fingerprint = hex.EncodeToString(h.Sum(nil))
+	return dict, nil
+}
+
+// 函数说明：恢复曾经成功过的密码的顺序（检查点保存的开始破解时的顺序）
+// 两次运行之间成功记录可能变化，恢复后候选顺序与上次相同，续跑位置之前的密码都已测试过
+// 参数：
+// hot: 曾经成功过的密码，须来自指纹相同的字典
+func (d *passwordDict) restoreHot(hot []string) {
+	d.hot = hot
+	d.hotSet = make(map[string]bool, len(hot))
+	for _, pass := range hot {
+		d.hotSet[pass] = true
+	}
+}
+
+// dictIterator 按顺序逐个读取字典中的密码：先是曾经成功过的密码，再按文件顺序读取其余密码
+type dictIterator struct {
+	dict    *passwordDict
+	dedup   *dedupSet
+	hotPos  int
+	fileIdx int
+	file    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+// iterate 从头开始读取字典
+func (d *passwordDict) iterate() *dictIterator {
+	return &dictIterator{dict: d, dedup: newDedupSet(d.suspects)}
+}
+
+// next 返回下一个密码，读完（或文件读取出错）时第二个返回值为 false
+func (it *dictIterator) next() (string, bool) {
+	if it.hotPos < len(it.dict.hot) {
+		it.hotPos++
+		return it.dict.hot[it.hotPos-1], true
+	}
+	for {
+		if it.scanner == nil {
+			if it.fileIdx >= len(it.dict.paths) {
+				return "", false
+			}
+			file, err := openPasswordFile(it.dict.paths[it.fileIdx])
+			it.fileIdx++
+			if err != nil {
+				continue
+			}
+			it.file = file
+			it.scanner = newPasswordScanner(file)
 		}
-		resultChan <- passwords
-	}()
 
-	select {
-	case passwords := <-resultChan:
-		return passwords, len(passwords), nil
-	case err := <-errorChan:
-		return nil, 0, err
-	case <-time.After(30 * time.Second): // 兜底：读取超时
-		return nil, 0, fmt.Errorf("读取文件超时")
+		if !it.scanner.Scan() {
+			it.closeFile()
+			continue
+		}
+		// 与 loadPasswordDict 的去重判断保持一致，保证位置与统计时相同
+		password := strings.TrimSpace(it.scanner.Text())
+		if password == "" || it.dedup.duplicate(password) || it.dict.hotSet[password] {
+			continue
+		}
+		return password, true
 	}
 }
 
-// scanPasswords 逐行读取密码文件，去除空白行
-func scanPasswords(path string) ([]string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
+// skip 跳过 n 个密码
+func (it *dictIterator) skip(n int64) {
+	for i := int64(0); i < n; i++ {
+		if _, ok := it.next(); !ok {
+			return
+		}
 	}
-	defer file.Close()
+}
 
-	var passwords []string
-	scanner := bufio.NewScanner(file)
+// rewind 回到字典开头（复用去重的内存）
+func (it *dictIterator) rewind() {
+	it.closeFile()
+	it.hotPos = 0
+	it.fileIdx = 0
+	it.dedup.reset()
+}
 
-	// 注意：设置更大的 buffer 以提高读取性能
-	const maxCapacity = 512 * 1024 // 512KB
-	buf := make([]byte, maxCapacity)
-	scanner.Buffer(buf, maxCapacity)
+// closeFile 关闭当前读取的文件
+func (it *dictIterator) closeFile() {
+	if it.file != nil {
+		it.file.Close()
+	}
+	it.file = nil
+	it.scanner = nil
+}
+
+// 字典目录：passwd.d 下的每个文件都是一个字典，manifest.json 可设置各字典的优先级、是否启用及适用的文件名
+const (
+	PASSWD_DIR_NAME      = "passwd.d"
+	PASSWD_MANIFEST_NAME = "manifest.json"
+)
+
+// dictManifest passwd.d/manifest.json 的内容
+type dictManifest struct {
+	Dictionaries []dictManifestEntry `json:"dictionaries"`
+}
+
+// dictManifestEntry 一个字典的设置，未在清单中列出的文件按默认设置使用
+type dictManifestEntry struct {
+	File     string `json:"file"`     // 文件名（相对 passwd.d）
+	Priority int    `json:"priority"` // 优先级，越大越先使用，默认 0
+	Enabled  *bool  `json:"enabled"`  // 是否启用，默认启用
+	Archive  string `json:"archive"`  // 只用于文件名匹配该通配符的压缩包，如 *[X]*，为空表示全部
+}
+
+// passwordFile 一个候选的密码文件
+type passwordFile struct {
+	path     string
+	priority int
+	skip     string // 不使用的原因，为空表示使用
+}
+
+// 函数说明：通配符匹配压缩包文件名（不区分大小写）
+// 只有 * 和 ? 是通配符，其余字符（包括 []）按原样匹配，方便写 *[X]* 这类论坛标签
+// 参数：
+// pattern: 通配符
+// name: 压缩包文件名
+// 返回：是否匹配
+func matchArchiveGlob(pattern, name string) bool {
+	var expr strings.Builder
+	expr.WriteString("(?i)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	matched, _ := regexp.MatchString(expr.String(), name)
+	return matched
+}
+
+// 函数说明：列出 passwd.d 目录中的字典
+// 参数：
+// dir: passwd.d 目录
+// archiveName: 压缩包文件名（用于匹配清单中的 archive，为空时只使用不限文件名的字典）
+// 返回：字典文件列表（清单中的按清单顺序，其余按文件名），错误信息（目录不存在不算错误）
+func listPasswordDir(dir string, archiveName string) ([]passwordFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取字典目录失败: %v", err)
+	}
 
-	for scanner.Scan() {
-		if password := strings.TrimSpace(scanner.Text()); password != "" {
-			passwords = append(passwords, password)
+	var manifest dictManifest
+	if data, err := os.ReadFile(filepath.Join(dir, PASSWD_MANIFEST_NAME)); err == nil {
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %v", filepath.Join(dir, PASSWD_MANIFEST_NAME), err)
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("读取文件失败: %v", err)
+	var files []passwordFile
+	listed := make(map[string]bool)
+	for _, entry := range manifest.Dictionaries {
+		file := passwordFile{path: filepath.Join(dir, entry.File), priority: entry.Priority}
+		listed[strings.ToLower(entry.File)] = true
+		switch {
+		case entry.Enabled != nil && !*entry.Enabled:
+			file.skip = "已禁用"
+		case entry.Archive != "" && !matchArchiveGlob(entry.Archive, archiveName):
+			file.skip = fmt.Sprintf("仅用于 %s", entry.Archive)
+		}
+		if _, err := os.Stat(file.path); err != nil && file.skip == "" {
+			file.skip = "文件不存在"
+		}
+		files = append(files, file)
 	}
 
-	return passwords, nil
+	// 未在清单中列出的文件按默认设置使用（os.ReadDir 已按文件名排序）
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.EqualFold(name, PASSWD_MANIFEST_NAME) || listed[strings.ToLower(name)] {
+			continue
+		}
+		files = append(files, passwordFile{path: filepath.Join(dir, name)})
+	}
+	return files, nil
 }
 
 // 函数说明：获取所有密码
-func getAllPasswords() ([]string, string, error) {
+// 密码文件的查找顺序：当前目录、程序目录的 passwd.txt（及 .gz/.zst/.7z/.vault 版本），再是这两个目录下 passwd.d 中的字典；
+// 之后按优先级（越大越先）稳定排序，同优先级保持上述顺序
+// 参数：
+// archivePath: 压缩文件路径（决定 passwd.d 中限定了文件名的字典是否使用，可为空）
+// 返回：字典（出错时为空字典，可直接使用），使用的密码文件信息，错误信息
+func getAllPasswords(archivePath string) (*passwordDict, string, error) {
 	// 获取可能的密码文件路径
 	exePath, _ := os.Executable()
 	exeDir := filepath.Dir(exePath)
 	currentDir, _ := os.Getwd()
+	archiveName := filepath.Base(archivePath)
+	if archivePath == "" {
+		archiveName = ""
+	}
 
-	// 按优先级顺序存储密码文件路径
-	dictPaths := []string{
-		filepath.Join(currentDir, "passwd.txt"), // 当前目录
-		filepath.Join(exeDir, "passwd.txt"),     // 程序目录
+	// 按优先级顺序存储密码文件路径：当前目录、程序目录（各目录中 passwd.txt 及其压缩、加密版本）
+	var candidates []passwordFile
+	for _, dir := range []string{currentDir, exeDir} {
+		for _, name := range PASSWD_FILE_NAMES {
+			candidates = append(candidates, passwordFile{path: filepath.Join(dir, name)})
+		}
+	}
+	for _, dir := range []string{currentDir, exeDir} {
+		files, err := listPasswordDir(filepath.Join(dir, PASSWD_DIR_NAME), archiveName)
+		if err != nil {
+			return &passwordDict{}, "", err
+		}
+		candidates = append(candidates, files...)
 	}
 
 	// 对路径进行去重
-	var uniquePaths []string
+	var files []passwordFile
 	seen := make(map[string]bool)
 
-	for _, path := range dictPaths {
-		absPath, err := filepath.Abs(path)
+	for _, file := range candidates {
+		absPath, err := filepath.Abs(file.path)
 		if err != nil {
 			continue
 		}
 		if !seen[absPath] {
 			seen[absPath] = true
-			uniquePaths = append(uniquePaths, path)
+			files = append(files, file)
 		}
 	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].priority > files[j].priority
+	})
 
-	// 用map去重
-	passwordMap := make(map[string]bool)
 	var usedPaths []string
-	filePasswords := make(map[string]int)
-
-	// 读取所有密码文件
-	for _, path := range uniquePaths {
-		if passwords, count, err := readPasswordFile(path); err == nil {
-			if count > 0 {
-				usedPaths = append(usedPaths, path)
-				filePasswords[path] = count
-				// 添加密码到map中去重
-				for _, password := range passwords {
-					passwordMap[password] = true
-				}
-			}
+	var skipped []passwordFile
+	priorities := make(map[string]int)
+	for _, file := range files {
+		if file.skip != "" {
+			skipped = append(skipped, file)
+			continue
 		}
+		usedPaths = append(usedPaths, file.path)
+		priorities[file.path] = file.priority
 	}
 
-	// 如果没有找到任何密码
-	if len(passwordMap) == 0 {
-		return nil, "", fmt.Errorf("未找到密码文件或密码为空")
+	dict, err := loadPasswordDict(usedPaths)
+	if err != nil {
+		return &passwordDict{}, "", err
 	}
 
-	// 转换map为切片
-	var uniquePasswords []string
-	for pass := range passwordMap {
-		uniquePasswords = append(uniquePasswords, pass)
+	// 如果没有找到任何密码
+	if dict.count == 0 {
+		return dict, "", fmt.Errorf("未找到密码文件或密码为空")
 	}
 
-	// 生成使用的密码文件信息
-	var usedPathsInfo string
-	if len(usedPaths) > 0 {
-		usedPathsInfo = "使用的密码文件:\n"
-		for i, path := range usedPaths {
-			usedPathsInfo += fmt.Sprintf("%d. %s (包含 %d 个密码)\n", i+1, path, filePasswords[path])
+	// 生成使用的密码文件信息（按使用顺序）
+	usedPathsInfo := "使用的密码文件:\n"
+	for i, path := range dict.paths {
+		usedPathsInfo += fmt.Sprintf("%d. %s (包含 %d 个密码", i+1, path, dict.fileCounts[path])
+		if priorities[path] != 0 {
+			usedPathsInfo += fmt.Sprintf("，优先级 %d", priorities[path])
 		}
-		usedPathsInfo += fmt.Sprintf("\n去重后共 %d 个密码", len(uniquePasswords))
+		usedPathsInfo += ")\n"
+	}
+	for _, file := range skipped {
+		usedPathsInfo += fmt.Sprintf("未使用: %s (%s)\n", file.path, file.skip)
+	}
+	usedPathsInfo += fmt.Sprintf("\n去重后共 %d 个密码", dict.count)
+	if len(dict.hot) > 0 {
+		usedPathsInfo += fmt.Sprintf("，其中 %d 个曾经成功过，优先尝试", len(dict.hot))
 	}
 
-	return uniquePasswords, usedPathsInfo, nil
+	return dict, usedPathsInfo, nil
 }
 
-// 函数说明：保存密码到passwd.txt文件
-// 参数：
-// password: 密码
-// 返回：错误信息
-func savePasswordToFile(password string) error {
-	// 获取程序路径
-	exePath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("获取程序路径失败: %v", err)
+// getPasswordSavePath 新密码的保存位置：程序目录有密码库时保存到密码库，否则保存到 passwd.txt
+func getPasswordSavePath() string {
+	exePath, _ := os.Executable()
+	exeDir := filepath.Dir(exePath)
+	if vaultPath := filepath.Join(exeDir, VAULT_FILE_NAME); fileExists(vaultPath) {
+		return vaultPath
 	}
+	return filepath.Join(exeDir, "passwd.txt")
+}
 
-	// 构建密码文件路径
-	passwdPath := filepath.Join(filepath.Dir(exePath), "passwd.txt")
+// fileExists 判断文件是否存在
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
 
-	// 检查密码是否已存在
-	content, err := os.ReadFile(passwdPath)
-	if err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("读取密码文件失败: %v", err)
-	}
+// 函数说明：保存密码到passwd.txt文件（程序目录有密码库时追加到密码库，不解密到磁盘）
+// 参数：
+// password: 密码
+// 返回：是否保存了（已存在时不重复保存），错误信息
+func savePasswordToFile(password string) (bool, error) {
+	return appendPasswordToFile(getPasswordSavePath(), password)
+}
 
-	// 如果文件存在，检查密码是否已在文件中（按行精确匹配）
-	if err == nil {
-		// 将内容分割成行
-		lines := strings.Split(string(content), "\n")
-		for _, line := range lines {
-			// 去除每行前后的空白字符
-			trimmedLine := strings.TrimSpace(line)
-			// 精确匹配整行
-			if trimmedLine == password {
-				return nil
-			}
+// 函数说明：把密码追加到密码文件末尾（已存在时不追加）
+// 参数：
+// passwdPath: 密码文件路径（明文或 .vault 密码库）
+// password: 密码
+// 返回：是否追加了，错误信息
+func appendPasswordToFile(passwdPath string, password string) (bool, error) {
+	if strings.EqualFold(filepath.Ext(passwdPath), ".vault") {
+		vault, err := getUnlockedVault(passwdPath)
+		if err != nil {
+			return false, err
 		}
+		return vault.appendPassword(password)
+	}
+
+	// 检查密码是否已存在（逐行读取，密码文件很大时也不占内存）
+	exists, endsWithNewline, err := findPasswordInFile(passwdPath, password)
+	if err != nil {
+		return false, fmt.Errorf("读取密码文件失败: %v", err)
+	}
+	if exists {
+		return false, nil
 	}
 
 	// 以追加模式打开文件
 	f, err := os.OpenFile(passwdPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		return fmt.Errorf("打开密码文件失败: %v", err)
+		return false, fmt.Errorf("打开密码文件失败: %v", err)
 	}
 	defer f.Close()
 
 	// 如果文件不为空且最后一个字符不是换行符，先写入换行符
-	if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+	if !endsWithNewline {
 		if _, err := f.WriteString("\n"); err != nil {
-			return fmt.Errorf("写入换行符失败: %v", err)
+			return false, fmt.Errorf("写入换行符失败: %v", err)
 		}
 	}
 
 	// 只追加新密码
 	if _, err := f.WriteString(password + "\n"); err != nil {
-		return fmt.Errorf("写入密码失败: %v", err)
+		return false, fmt.Errorf("写入密码失败: %v", err)
+	}
+
+	return true, nil
+}
+
+// 函数说明：在密码文件中按行精确查找密码（去除每行前后的空白字符）
+// 参数：
+// path: 密码文件路径
+// password: 密码
+// 返回：是否存在，文件是否为空或以换行符结尾，错误信息（文件不存在不算错误）
+func findPasswordInFile(path string, password string) (bool, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, true, nil
+		}
+		return false, false, err
 	}
+	defer file.Close()
 
-	return nil
+	endsWithNewline := true
+	reader := bufio.NewReaderSize(file, 64*1024)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			endsWithNewline = strings.HasSuffix(line, "\n")
+			if strings.TrimSpace(line) == password {
+				return true, endsWithNewline, nil
+			}
+		}
+		if err == io.EOF {
+			return false, endsWithNewline, nil
+		}
+		if err != nil {
+			return false, false, err
+		}
+	}
 }
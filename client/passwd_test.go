@@ -0,0 +1,97 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchArchiveGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*[X]*", "game [X] v1.rar", true},
+		{"*[X]*", "game X v1.rar", false}, // [] 按原样匹配，不是字符类
+		{"*.rar", "TEST.RAR", true},
+		{"*.rar", "test.rar.txt", false},
+		{"test.???", "test.zip", true},
+		{"test.???", "test.gz", false},
+		{"a.b", "axb", false},
+		{"(a)+", "(a)+", true},
+		{"资料*", "资料合集.7z", true},
+		{"?.zip", "资.zip", true}, // ? 匹配一个字符而不是一个字节
+		{"*", "", true},
+		{"", "a.rar", false},
+	}
+	for _, tt := range tests {
+		if got := matchArchiveGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("%q 匹配 %q: 返回 %v，应为 %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestListPasswordDir(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"a.txt":     "a",
+		"b.txt":     "b",
+		"c.txt":     "c",
+		"d.txt":     "d",
+		".hidden":   "h",
+		"sub/e.txt": "e",
+		PASSWD_MANIFEST_NAME: `{"dictionaries": [
+			{"file": "c.txt", "priority": 5},
+			{"file": "B.TXT", "enabled": false},
+			{"file": "missing.txt"},
+			{"file": "a.txt", "priority": 1, "archive": "*[X]*"}
+		]}`,
+	})
+	tests := []struct {
+		archiveName string
+		want        []passwordFile
+	}{
+		{"test.rar", []passwordFile{
+			{path: "c.txt", priority: 5},
+			{path: "B.TXT", skip: "已禁用"},
+			{path: "missing.txt", skip: "文件不存在"},
+			{path: "a.txt", priority: 1, skip: "仅用于 *[X]*"},
+			{path: "d.txt"}, // 未在清单中列出的按默认设置使用
+		}},
+		{"game [x].rar", []passwordFile{
+			{path: "c.txt", priority: 5},
+			{path: "B.TXT", skip: "已禁用"},
+			{path: "missing.txt", skip: "文件不存在"},
+			{path: "a.txt", priority: 1},
+			{path: "d.txt"},
+		}},
+		{"", []passwordFile{ // 不知道压缩包文件名时只用不限文件名的字典
+			{path: "c.txt", priority: 5},
+			{path: "B.TXT", skip: "已禁用"},
+			{path: "missing.txt", skip: "文件不存在"},
+			{path: "a.txt", priority: 1, skip: "仅用于 *[X]*"},
+			{path: "d.txt"},
+		}},
+	}
+	for _, tt := range tests {
+		files, err := listPasswordDir(dir, tt.archiveName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range files {
+			files[i].path, _ = filepath.Rel(dir, files[i].path)
+		}
+		if !reflect.DeepEqual(files, tt.want) {
+			t.Errorf("%q: 列出 %+v，应为 %+v", tt.archiveName, files, tt.want)
+		}
+	}
+
+	if files, err := listPasswordDir(filepath.Join(dir, "none"), "test.rar"); err != nil || files != nil {
+		t.Errorf("目录不存在时返回 %v, %v", files, err)
+	}
+	writeTestTree(t, dir, map[string]string{PASSWD_MANIFEST_NAME: "{"})
+	if _, err := listPasswordDir(dir, "test.rar"); err == nil {
+		t.Error("清单格式错误时应返回错误")
+	}
+}
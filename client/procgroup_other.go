@@ -0,0 +1,23 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup 让 7z 在独立的进程组中运行：终端的 Ctrl+C 不直接发给 7z，由 7zrpw 统一取消
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessTree 结束进程所在的整个进程组
+func killProcessTree(p *os.Process) error {
+	// 负的进程号表示进程组
+	if err := syscall.Kill(-p.Pid, syscall.SIGKILL); err != nil {
+		return p.Kill()
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 进度显示
+const (
+	progressBarWidth = 30
+	progressInterval = 200 * time.Millisecond // 两次刷新的最短间隔
+)
+
+// ExtractProgress 解压进度
+type ExtractProgress struct {
+	Percent int           // 完成百分比 0~100
+	File    string        // 当前文件（包内路径）
+	Files   int           // 已处理的文件数
+	Bytes   int64         // 已解压的字节数（7z 按百分比和总大小估算）
+	Total   int64         // 解压后的总字节数，未知时为 0
+	Elapsed time.Duration // 已用时间
+}
+
+// ProgressFunc 接收解压进度，控制台之外的前端（如图形界面）可以传入自己的实现
+type ProgressFunc func(p ExtractProgress)
+
+// speed 平均解压速度（字节/秒），无法计算时为 0
+func (p ExtractProgress) speed() float64 {
+	if p.Bytes <= 0 || p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Bytes) / p.Elapsed.Seconds()
+}
+
+// eta 按已用时间和完成百分比估算的剩余时间，无法估算时返回 false
+func (p ExtractProgress) eta() (time.Duration, bool) {
+	if p.Percent <= 0 || p.Percent >= 100 || p.Elapsed < time.Second {
+		return 0, false
+	}
+	return p.Elapsed * time.Duration(100-p.Percent) / time.Duration(p.Percent), true
+}
+
+// 7z -bsp1 的进度行，如 " 45% 12 - dir\file.txt"（百分比、已处理文件数、当前文件）
+var reSevenZipProgress = regexp.MustCompile(`^\s*(\d{1,3})%(?:\s+(\d+))?(?:\s+[-+=U]\s+(.*))?$`)
+
+// 7z -bb1 的文件日志行，如 "- dir/file.txt"
+var reSevenZipFileLog = regexp.MustCompile(`^- (.+)$`)
+
+// 函数说明：按 \r、\n、\b 切分 7z 输出（进度行靠回车或退格覆盖刷新），供 bufio.Scanner 使用
+func scanProgressSegments(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexAny(data, "\r\n\b"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// 函数说明：解析 7z 输出的一段，更新进度
+// 参数：
+// segment: 一段输出
+// p: 进度
+// 返回：是否是进度信息
+func parseSevenZipProgress(segment string, p *ExtractProgress) bool {
+	segment = strings.TrimRight(segment, " ")
+	if m := reSevenZipProgress.FindStringSubmatch(segment); m != nil {
+		p.Percent, _ = strconv.Atoi(m[1])
+		p.Percent = min(p.Percent, 100)
+		if m[2] != "" {
+			p.Files, _ = strconv.Atoi(m[2])
+		}
+		if m[3] != "" {
+			p.File = decodeGBK(m[3])
+		}
+		if p.Total > 0 {
+			p.Bytes = p.Total * int64(p.Percent) / 100
+		}
+		return true
+	}
+	if m := reSevenZipFileLog.FindStringSubmatch(segment); m != nil {
+		p.File = decodeGBK(m[1])
+		return true
+	}
+	return false
+}
+
+// progressThrottle 限制进度回调的频率
+type progressThrottle struct {
+	onProgress ProgressFunc
+	startTime  time.Time
+	last       time.Time
+}
+
+func newProgressThrottle(onProgress ProgressFunc) *progressThrottle {
+	return &progressThrottle{onProgress: onProgress, startTime: time.Now()}
+}
+
+// report 距上次回调超过 progressInterval 或 force 时回调（填入已用时间）
+func (t *progressThrottle) report(p ExtractProgress, force bool) {
+	if t == nil || t.onProgress == nil {
+		return
+	}
+	now := time.Now()
+	if !force && now.Sub(t.last) < progressInterval {
+		return
+	}
+	t.last = now
+	p.Elapsed = now.Sub(t.startTime)
+	t.onProgress(p)
+}
+
+// 函数说明：格式化进度条
+// 参数：
+// p: 解压进度
+// 返回：进度显示字符串（以 \r 开头，覆盖上一次的显示）
+func formatExtractProgress(p ExtractProgress) string {
+	filled := progressBarWidth * p.Percent / 100
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "解压中 [%s] %3d%%", bar, p.Percent)
+	if p.Total > 0 {
+		fmt.Fprintf(&b, " %s/%s", formatFileSize(p.Bytes), formatFileSize(p.Total))
+	} else if p.Bytes > 0 {
+		fmt.Fprintf(&b, " %s", formatFileSize(p.Bytes))
+	}
+	if speed := p.speed(); speed > 0 {
+		fmt.Fprintf(&b, " %s/秒", formatFileSize(int64(speed)))
+	}
+	if eta, ok := p.eta(); ok {
+		fmt.Fprintf(&b, " 剩余 %s", formatDuration(eta))
+	} else {
+		fmt.Fprintf(&b, " 已用时 %s", formatDuration(p.Elapsed))
+	}
+	if p.File != "" {
+		fmt.Fprintf(&b, " %s", filepath.Base(filepath.FromSlash(p.File)))
+	}
+
+	// 先清除整行，再显示新内容
+	return fmt.Sprintf("\r%s\r%s", strings.Repeat(" ", 100), b.String())
+}
+
+// 函数说明：控制台进度显示
+// 返回：进度回调
+func consoleProgress() ProgressFunc {
+	return func(p ExtractProgress) {
+		fmt.Print(formatExtractProgress(p))
+	}
+}
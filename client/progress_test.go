@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParseSevenZipProgress(t *testing.T) {
+	tests := []struct {
+		segment string
+		ok      bool
+		want    ExtractProgress
+	}{
+		{" 45% 12 - dir\\file.txt", true, ExtractProgress{Percent: 45, Files: 12, File: "dir\\file.txt", Bytes: 450, Total: 1000}},
+		{"  7%", true, ExtractProgress{Percent: 7, File: "old.txt", Bytes: 70, Total: 1000}},
+		{"100% 3", true, ExtractProgress{Percent: 100, Files: 3, File: "old.txt", Bytes: 1000, Total: 1000}},
+		{" 12% 2 + a b.txt   ", true, ExtractProgress{Percent: 12, Files: 2, File: "a b.txt", Bytes: 120, Total: 1000}},
+		{"- dir/file.txt", true, ExtractProgress{File: "dir/file.txt", Total: 1000}},
+		{"Everything is Ok", false, ExtractProgress{File: "old.txt", Total: 1000}},
+		{"Extracting archive: test.7z", false, ExtractProgress{File: "old.txt", Total: 1000}},
+		{"Size: 1000", false, ExtractProgress{File: "old.txt", Total: 1000}},
+		{"1000%", false, ExtractProgress{File: "old.txt", Total: 1000}},
+	}
+	for _, tt := range tests {
+		p := ExtractProgress{File: "old.txt", Total: 1000}
+		if ok := parseSevenZipProgress(tt.segment, &p); ok != tt.ok {
+			t.Errorf("%q: 返回 %v，应为 %v", tt.segment, ok, tt.ok)
+		}
+		if p != tt.want {
+			t.Errorf("%q: 进度为 %+v，应为 %+v", tt.segment, p, tt.want)
+		}
+	}
+}
+
+// 进度行以 \b 或 \r 覆盖刷新，一段输出可能分几次读到
+func TestScanProgressSegments(t *testing.T) {
+	output := "  0%\b\b\b\b    \b\b\b\b 12% 1 - a.txt\r 45% 2 - dir\\b.txt\r\n- c.txt\nEverything is Ok\n 99%"
+	// 每次只读 1 个字节，段在多次读取之间被切开
+	scanner := bufio.NewScanner(iotest.OneByteReader(strings.NewReader(output)))
+	scanner.Split(scanProgressSegments)
+	var segments []string
+	for scanner.Scan() {
+		if segment := strings.TrimSpace(scanner.Text()); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"0%", "12% 1 - a.txt", "45% 2 - dir\\b.txt", "- c.txt", "Everything is Ok", "99%"}
+	if strings.Join(segments, "|") != strings.Join(want, "|") {
+		t.Errorf("切分为 %q，应为 %q", segments, want)
+	}
+
+	// 没有分隔符时等待更多数据，读完时返回剩余部分
+	if advance, token, _ := scanProgressSegments([]byte(" 45% 2 - a"), false); advance != 0 || token != nil {
+		t.Errorf("数据不完整时返回 %d, %q", advance, token)
+	}
+	if advance, token, _ := scanProgressSegments([]byte(" 45%"), true); advance != 4 || string(token) != " 45%" {
+		t.Errorf("读完时返回 %d, %q", advance, token)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
@@ -32,27 +33,26 @@ type ClientConfig struct {
 // password: 密码
 // 返回：是否成功
 func reportPassword(archivePath, password string) bool {
-	sendPasswordToServer(serverURL, appKey, appSecret, archivePath, password)
+	url, key, secret := getServerSettings()
+	sendPasswordToServer(url, key, secret, archivePath, password)
 	return true
 }
 
-func sendPasswordToServer(serverURL, appKey, appSecret, filePath, password string) error {
-
-	// 打开文件
-	file, err := os.Open(filePath)
-	if err != nil {
-		if debugMode {
-			return fmt.Errorf("打开文件失败: %v", err)
-		}
-		return err
+// getServerSettings 服务器地址与签名密钥：7zrpw.json 中配置了 server_url 时使用自建服务器，否则使用构建时注入的值
+func getServerSettings() (string, string, string) {
+	if appConfig.ServerURL != "" {
+		return appConfig.ServerURL, appConfig.AppKey, appConfig.AppSecret
 	}
-	defer file.Close()
+	return serverURL, appKey, appSecret
+}
 
-	// 获取文件信息
-	fileInfo, err := file.Stat()
+func sendPasswordToServer(serverURL, appKey, appSecret, filePath, password string) error {
+
+	// 计算文件指纹
+	fp, err := getFileFingerprint(filePath)
 	if err != nil {
 		if debugMode {
-			return fmt.Errorf("获取文件信息失败: %v", err)
+			return fmt.Errorf("计算文件指纹失败: %v", err)
 		}
 		return err
 	}
@@ -60,36 +60,13 @@ func sendPasswordToServer(serverURL, appKey, appSecret, filePath, password strin
 	// 获取文件类型
 	fileType := getFileTypeDesc(getFileType(filePath))
 
-	// 计算前1024字节的MD5
-	hash := md5.New()
-	buffer := make([]byte, 1024)
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
-		return err
-	}
-	hash.Write(buffer[:n])
-	md51024 := hex.EncodeToString(hash.Sum(nil))
-
-	// 重置文件指针
-	file.Seek(0, 0)
-
-	// 计算前1MB的MD5
-	hash = md5.New()
-	buffer = make([]byte, 1024*1024)
-	n, err = file.Read(buffer)
-	if err != nil && err != io.EOF {
-		return err
-	}
-	hash.Write(buffer[:n])
-	md51mb := hex.EncodeToString(hash.Sum(nil))
-
 	uuid := loadOrGenerateUUID()
 	// 准备请求参数
 	params := map[string]interface{}{
 		"name_raw":  filepath.Base(filePath),
-		"size":      fileInfo.Size(),
-		"md5_1024":  md51024,
-		"md5_1mb":   md51mb,
+		"size":      fp.Size,
+		"md5_1024":  fp.MD5_1024,
+		"md5_1mb":   fp.MD5_1MB,
 		"password":  password,
 		"uuid":      uuid,
 		"file_type": fileType,
@@ -117,6 +94,154 @@ func sendPasswordToServer(serverURL, appKey, appSecret, filePath, password strin
 	return nil
 }
 
+// 服务器查询的限制：查询只是锦上添花，离线或服务器无响应时很快放弃
+const (
+	defaultLookupTimeout     = 3 * time.Second
+	lookupMaxPasswords       = 20
+	lookupMaxResponseSize    = 64 * 1024
+	LOOKUP_ACTION            = "lookup"
+	lookupUnconfiguredServer = "default"
+)
+
+// lookupResponse 查询接口的响应
+type lookupResponse struct {
+	Passwords []string `json:"passwords"`
+}
+
+// 函数说明：向服务器查询其他用户为同一个压缩包（按文件指纹）找到的密码
+// 与上报使用同一个地址和 JWT 签名，参数中的 action 为 lookup
+// 参数：
+// ctx: 上下文（取消后中止请求）
+// client: HTTP 客户端（由调用方设置超时）
+// serverURL: 服务器地址
+// appKey: 应用标识
+// appSecret: JWT 签名密钥
+// fp: 文件指纹
+// 返回：候选密码，错误信息
+func lookupPasswordsFromServer(ctx context.Context, client *http.Client, serverURL, appKey, appSecret string, fp fileFingerprint) ([]string, error) {
+	params := map[string]interface{}{
+		"action":   LOOKUP_ACTION,
+		"size":     fp.Size,
+		"md5_1024": fp.MD5_1024,
+		"md5_1mb":  fp.MD5_1MB,
+		"uuid":     loadOrGenerateUUID(),
+	}
+	token, err := generateJWT(appKey, appSecret, params)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", serverURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("服务器返回 %s", resp.Status)
+	}
+
+	var result lookupResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, lookupMaxResponseSize)).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析服务器响应失败: %v", err)
+	}
+	var passwords []string
+	for _, password := range result.Passwords {
+		if password != "" && len(passwords) < lookupMaxPasswords {
+			passwords = append(passwords, password)
+		}
+	}
+	return passwords, nil
+}
+
+// 函数说明：破解前向服务器查询该压缩包的密码（需在配置中开启 server_lookup 或使用 --lookup）
+// 离线、超时或服务器出错时只提示一行，不影响后续破解
+// 参数：
+// ctx: 上下文（Ctrl+C 时中止查询）
+// archivePath: 压缩文件路径
+// 返回：候选密码
+func findServerPasswords(ctx context.Context, archivePath string) []string {
+	url, key, secret := getServerSettings()
+	if !appConfig.ServerLookup || url == "" || url == lookupUnconfiguredServer {
+		return nil
+	}
+	fp, err := getFileFingerprint(archivePath)
+	if err != nil {
+		return nil
+	}
+
+	timeout := defaultLookupTimeout
+	if appConfig.LookupTimeout > 0 {
+		timeout = time.Duration(appConfig.LookupTimeout) * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+	passwords, err := lookupPasswordsFromServer(ctx, client, url, key, secret, fp)
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		fmt.Printf("查询服务器失败，跳过: %v\n", err)
+		return nil
+	}
+	return passwords
+}
+
+// fileFingerprint 文件指纹：文件大小 + 前 1024 字节和前 1MB 的 MD5，用于在不读取整个文件的情况下识别同一个压缩包
+type fileFingerprint struct {
+	Size     int64  `json:"size"`
+	MD5_1024 string `json:"md5_1024"`
+	MD5_1MB  string `json:"md5_1mb"`
+}
+
+// 函数说明：计算文件指纹
+// 参数：
+// filePath: 文件路径
+// 返回：文件指纹，错误信息
+func getFileFingerprint(filePath string) (fileFingerprint, error) {
+	var fp fileFingerprint
+
+	// 打开文件
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fp, err
+	}
+	defer file.Close()
+
+	// 获取文件信息
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return fp, err
+	}
+	fp.Size = fileInfo.Size()
+
+	// 读取前1MB（前1024字节的MD5取其开头部分）
+	buffer := make([]byte, 1024*1024)
+	n, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fp, err
+	}
+	buffer = buffer[:n]
+
+	// 计算前1024字节的MD5
+	head := buffer
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	sum := md5.Sum(head)
+	fp.MD5_1024 = hex.EncodeToString(sum[:])
+
+	// 计算前1MB的MD5
+	sum = md5.Sum(buffer)
+	fp.MD5_1MB = hex.EncodeToString(sum[:])
+
+	return fp, nil
+}
+
 // 生成JWT令牌
 func generateJWT(appKey, appSecret string, params map[string]interface{}) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testAppKey    = "test-key"
+	testAppSecret = "test-secret"
+)
+
+var testFingerprint = fileFingerprint{Size: 1234, MD5_1024: "aaaa", MD5_1MB: "bbbb"}
+
+// newLookupServer 验证查询请求的 JWT 后交给 respond 处理
+func newLookupServer(t *testing.T, respond func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	t.Helper()
+	// UUID 保存在临时目录，测试时不写到真实的临时目录
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+	t.Setenv("TMP", dir)
+	t.Setenv("TEMP", dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenString, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			http.Error(w, "missing token", http.StatusUnauthorized)
+			return
+		}
+		claims := jwt.MapClaims{}
+		_, err := jwt.ParseWithClaims(tokenString, claims, func(*jwt.Token) (interface{}, error) {
+			return []byte(testAppSecret), nil
+		}, jwt.WithValidMethods([]string{"HS256"}))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		params, _ := claims["params"].(map[string]interface{})
+		if claims["app_key"] != testAppKey || params["action"] != LOOKUP_ACTION ||
+			params["md5_1mb"] != testFingerprint.MD5_1MB || params["size"] != float64(testFingerprint.Size) {
+			http.Error(w, fmt.Sprintf("unexpected claims: %v", claims), http.StatusBadRequest)
+			return
+		}
+		respond(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// writeLookupPasswords 返回密码列表
+func writeLookupPasswords(w http.ResponseWriter, passwords []string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lookupResponse{Passwords: passwords})
+}
+
+func TestLookupPasswordsFromServer(t *testing.T) {
+	many := make([]string, lookupMaxPasswords+10)
+	for i := range many {
+		many[i] = fmt.Sprintf("pass%d", i)
+	}
+	huge := []string{strings.Repeat("x", lookupMaxResponseSize)}
+
+	tests := []struct {
+		name    string
+		respond func(w http.ResponseWriter, r *http.Request)
+		timeout time.Duration
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "正常响应",
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				writeLookupPasswords(w, []string{"abc", "", "密码123"})
+			},
+			want: []string{"abc", "密码123"},
+		},
+		{
+			name: "非 200",
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "boom", http.StatusInternalServerError)
+			},
+			wantErr: true,
+		},
+		{
+			name: "响应超过 64KB",
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				writeLookupPasswords(w, huge)
+			},
+			wantErr: true,
+		},
+		{
+			name: "超时",
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			},
+			timeout: 100 * time.Millisecond,
+			wantErr: true,
+		},
+		{
+			name: "密码多于上限",
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				writeLookupPasswords(w, many)
+			},
+			want: many[:lookupMaxPasswords],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newLookupServer(t, tt.respond)
+			timeout := tt.timeout
+			if timeout == 0 {
+				timeout = 5 * time.Second
+			}
+			client := &http.Client{Timeout: timeout}
+
+			start := time.Now()
+			got, err := lookupPasswordsFromServer(context.Background(), client, server.URL, testAppKey, testAppSecret, testFingerprint)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("应返回错误，得到 %v", got)
+				}
+				if elapsed := time.Since(start); elapsed > 2*time.Second {
+					t.Errorf("出错前等待了 %s", elapsed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("得到 %q，应为 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Ctrl+C 取消上下文后查询立即中止
+func TestLookupPasswordsFromServerCancel(t *testing.T) {
+	server := newLookupServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := lookupPasswordsFromServer(ctx, &http.Client{Timeout: 5 * time.Second}, server.URL, testAppKey, testAppSecret, testFingerprint)
+	if err == nil {
+		t.Fatal("取消后应返回错误")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("取消后仍等待了 %s", elapsed)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SevenZipResult 7z 命令的执行结果类型
+type SevenZipResult int
+
+// 7z 执行结果
+const (
+	RESULT_OK             SevenZipResult = iota // 成功
+	RESULT_WRONG_PASSWORD                       // 密码错误
+	RESULT_CORRUPT_DATA                         // 数据损坏
+	RESULT_MISSING_VOLUME                       // 缺少分卷或文件不完整
+	RESULT_UNSUPPORTED                          // 不支持的格式或压缩方法
+	RESULT_UNKNOWN                              // 无法确认（超时或被取消）
+	RESULT_FAILED                               // 其他错误（命令行错误、内存不足等）
+)
+
+// 7z 退出码（见 7-Zip 文档 Exit Codes）
+const (
+	EXIT_OK           = 0   // 无错误
+	EXIT_WARNING      = 1   // 警告（非致命错误）
+	EXIT_FATAL        = 2   // 致命错误
+	EXIT_COMMAND_LINE = 7   // 命令行错误
+	EXIT_MEMORY       = 8   // 内存不足
+	EXIT_USER_STOPPED = 255 // 用户中止
+)
+
+// 7z 错误输出（-bse2）中的错误类型标识，均为 7-Zip 源码中固定的英文提示，按优先级匹配。
+// 注意："Data Error in encrypted file. Wrong password?" 这类提示需先归为密码错误
+var sevenZipErrorKinds = []struct {
+	marker string
+	result SevenZipResult
+}{
+	{"Wrong password", RESULT_WRONG_PASSWORD},
+	{"Missing volume", RESULT_MISSING_VOLUME},
+	{"Unexpected end of archive", RESULT_MISSING_VOLUME},
+	{"Unsupported Method", RESULT_UNSUPPORTED},
+	{"Unsupported feature", RESULT_UNSUPPORTED},
+	{"open the file as archive", RESULT_UNSUPPORTED},
+	{"open as archive", RESULT_UNSUPPORTED},
+	{"Headers Error", RESULT_CORRUPT_DATA},
+	{"Data Error", RESULT_CORRUPT_DATA},
+	{"CRC Failed", RESULT_CORRUPT_DATA},
+}
+
+// sevenZipOutput 一次 7z 调用的输出
+type sevenZipOutput struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Result   SevenZipResult
+}
+
+// archiveError 与密码无关的压缩包问题（或解压失败），携带 7z 结果类型供调用方分别处理
+type archiveError struct {
+	Result SevenZipResult
+	Detail string // 7z 错误输出的摘要
+}
+
+func (e *archiveError) Error() string {
+	if e.Detail == "" {
+		return getResultDesc(e.Result)
+	}
+	return fmt.Sprintf("%s（%s）", getResultDesc(e.Result), e.Detail)
+}
+
+// run7z 用当前系统的 7z 运行命令（注释、流式解压字典等只有 7z 才有的功能）
+func run7z(ctx context.Context, args ...string) sevenZipOutput {
+	return sevenZip.run(ctx, args...)
+}
+
+// 函数说明：按退出码和错误输出归类 7z 结果
+// 参数：
+// exitCode: 7z 退出码
+// stderr: 7z 错误输出
+// 返回：结果类型
+func classify7zResult(exitCode int, stderr string) SevenZipResult {
+	switch exitCode {
+	case EXIT_OK, EXIT_WARNING:
+		// 警告（如压缩包末尾有多余数据）不影响数据完整性
+		return RESULT_OK
+	case EXIT_COMMAND_LINE, EXIT_MEMORY, EXIT_USER_STOPPED:
+		return RESULT_FAILED
+	}
+
+	for _, kind := range sevenZipErrorKinds {
+		if strings.Contains(stderr, kind.marker) {
+			return kind.result
+		}
+	}
+	return RESULT_FAILED
+}
+
+// errorDetail 提取错误输出中的有效行作为错误摘要
+func (o sevenZipOutput) errorDetail() string {
+	var lines []string
+	for _, line := range strings.Split(o.Stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "ERROR:" {
+			continue
+		}
+		lines = append(lines, line)
+		if len(lines) >= 3 {
+			break
+		}
+	}
+	return decodeGBK(strings.Join(lines, "; "))
+}
+
+// 函数说明：获取结果类型描述
+// 参数：
+// result: 结果类型
+// 返回：结果类型描述
+func getResultDesc(result SevenZipResult) string {
+	switch result {
+	case RESULT_OK:
+		return "成功"
+	case RESULT_WRONG_PASSWORD:
+		return "密码错误"
+	case RESULT_CORRUPT_DATA:
+		return "压缩包数据损坏"
+	case RESULT_MISSING_VOLUME:
+		return "缺少分卷或文件不完整"
+	case RESULT_UNSUPPORTED:
+		return "不支持的格式或压缩方法"
+	case RESULT_UNKNOWN:
+		return "无法确认"
+	default:
+		return "操作失败"
+	}
+}
+
+// 函数说明：获取结果类型对应的处理建议
+// 参数：
+// result: 结果类型
+// 返回：处理建议，无建议时为空
+func getResultHint(result SevenZipResult) string {
+	switch result {
+	case RESULT_WRONG_PASSWORD:
+		return "请确认密码是否正确"
+	case RESULT_CORRUPT_DATA:
+		return "压缩包可能已损坏，请重新下载"
+	case RESULT_MISSING_VOLUME:
+		return "请确认所有分卷都已下载完整，并放在同一目录下"
+	case RESULT_UNSUPPORTED:
+		return "该文件不是受支持的压缩包，或使用了 7z 不支持的压缩方法"
+	case RESULT_FAILED:
+		return "请检查 7z 是否被杀毒软件拦截，或磁盘空间、内存是否充足，解压目录是否可写"
+	default:
+		return ""
+	}
+}
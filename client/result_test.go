@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+// 错误输出摘自 7-Zip 实际的错误输出（-bse2），提示文字与 7-Zip 源码中的一致
+func TestClassify7zResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		exitCode int
+		stderr   string
+		want     SevenZipResult
+	}{
+		{"成功", 0, "", RESULT_OK},
+		{"警告", 1, "WARNINGS:\nThere are data after the end of archive\n", RESULT_OK},
+		{"文件名加密的 7z 密码错误", 2,
+			"ERROR: /tmp/test.7z\nCannot open encrypted archive. Wrong password?\n", RESULT_WRONG_PASSWORD},
+		{"AES zip 密码错误", 2, "ERROR: Wrong password : secret.txt\n", RESULT_WRONG_PASSWORD},
+		{"加密文件数据错误", 2,
+			"ERROR: Data Error in encrypted file. Wrong password? : secret.txt\n", RESULT_WRONG_PASSWORD},
+		{"加密文件 CRC 错误", 2,
+			"ERROR: CRC Failed in encrypted file. Wrong password? : secret.txt\n", RESULT_WRONG_PASSWORD},
+		{"CRC 错误", 2, "ERROR: CRC Failed : data.bin\n", RESULT_CORRUPT_DATA},
+		{"数据错误", 2, "ERROR: Data Error : data.bin\n", RESULT_CORRUPT_DATA},
+		{"头错误", 2, "ERROR: /tmp/test.7z\n/tmp/test.7z\nOpen ERROR: Headers Error\n", RESULT_CORRUPT_DATA},
+		{"缺少分卷", 2, "ERROR: Missing volume : test.7z.002\n", RESULT_MISSING_VOLUME},
+		{"文件不完整", 2, "ERROR: /tmp/test.7z\nUnexpected end of archive\n", RESULT_MISSING_VOLUME},
+		{"不支持的压缩方法", 2, "ERROR: Unsupported Method : data.bin\n", RESULT_UNSUPPORTED},
+		{"不是压缩包", 2, "ERROR: /tmp/test.bin\nCan not open the file as archive\n", RESULT_UNSUPPORTED},
+		{"无法识别的致命错误", 2, "ERROR: something else\n", RESULT_FAILED},
+		{"命令行错误", 7, "Command Line Error:\nUnsupported switch: -bse2\n", RESULT_FAILED},
+		{"内存不足", 8, "ERROR: Can't allocate required memory!\n", RESULT_FAILED},
+		{"用户中止", 255, "Break signaled\n", RESULT_FAILED},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classify7zResult(tt.exitCode, tt.stderr); got != tt.want {
+				t.Errorf("结果为 %s，应为 %s", getResultDesc(got), getResultDesc(tt.want))
+			}
+		})
+	}
+}
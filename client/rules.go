@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// RULE_FILE_NAME 规则文件名，与 passwd.txt 放在同一目录
+const RULE_FILE_NAME = "passwd.rule"
+
+// ruleOp 一个规则函数：函数字符及其参数（位置参数已换算成数字）
+type ruleOp struct {
+	code rune
+	n, m int  // 位置/次数参数
+	x, y rune // 字符参数
+}
+
+// passwordRule 一条规则（一行），由若干规则函数按顺序组成
+type passwordRule struct {
+	text string
+	ops  []ruleOp
+}
+
+// 规则函数的参数类型
+const (
+	ruleArgNone    = iota // 无参数
+	ruleArgPos            // N：位置或次数（0-9、A-Z 表示 0-35）
+	ruleArgChar           // X：一个字符
+	ruleArgTwoChar        // XY：两个字符
+	ruleArgPosChar        // NX：位置 + 字符
+	ruleArgTwoPos         // NM：两个位置或次数
+)
+
+// ruleArgCount 各参数类型占用的字符数
+var ruleArgCount = [...]int{ruleArgNone: 0, ruleArgPos: 1, ruleArgChar: 1, ruleArgTwoChar: 2, ruleArgPosChar: 2, ruleArgTwoPos: 2}
+
+// ruleFunctions 支持的规则函数（hashcat 规则语言的常用子集）
+var ruleFunctions = map[rune]int{
+	':':  ruleArgNone,    // 不变
+	'l':  ruleArgNone,    // 全部小写
+	'u':  ruleArgNone,    // 全部大写
+	'c':  ruleArgNone,    // 首字母大写，其余小写
+	'C':  ruleArgNone,    // 首字母小写，其余大写
+	't':  ruleArgNone,    // 全部切换大小写
+	'T':  ruleArgPos,     // 切换第 N 个字符的大小写
+	'r':  ruleArgNone,    // 反转
+	'd':  ruleArgNone,    // 重复一次：abc -> abcabc
+	'p':  ruleArgPos,     // 追加 N 次自身
+	'f':  ruleArgNone,    // 追加反转：abc -> abccba
+	'{':  ruleArgNone,    // 循环左移
+	'}':  ruleArgNone,    // 循环右移
+	'[':  ruleArgNone,    // 删除首字符
+	']':  ruleArgNone,    // 删除末字符
+	'D':  ruleArgPos,     // 删除第 N 个字符
+	'\'': ruleArgPos,     // 截断为前 N 个字符
+	'q':  ruleArgNone,    // 每个字符重复一次：abc -> aabbcc
+	'z':  ruleArgPos,     // 首字符重复 N 次：abc -> aaabc（z2）
+	'Z':  ruleArgPos,     // 末字符重复 N 次：abc -> abccc（Z2）
+	'y':  ruleArgPos,     // 重复前 N 个字符：abc -> ababc（y2）
+	'Y':  ruleArgPos,     // 重复后 N 个字符：abc -> abcbc（Y2）
+	'k':  ruleArgNone,    // 交换前两个字符
+	'K':  ruleArgNone,    // 交换后两个字符
+	'*':  ruleArgTwoPos,  // 交换第 N 和第 M 个字符
+	'x':  ruleArgTwoPos,  // 从第 N 个字符起截取 M 个字符
+	'O':  ruleArgTwoPos,  // 从第 N 个字符起删除 M 个字符
+	'+':  ruleArgPos,     // 第 N 个字符的编码加 1
+	'-':  ruleArgPos,     // 第 N 个字符的编码减 1
+	'$':  ruleArgChar,    // 末尾追加字符 X
+	'^':  ruleArgChar,    // 开头插入字符 X
+	'@':  ruleArgChar,    // 删除所有字符 X
+	's':  ruleArgTwoChar, // 把所有 X 替换为 Y（如 sa@ 为 leet 替换）
+	'i':  ruleArgPosChar, // 在第 N 个位置插入字符 X
+	'o':  ruleArgPosChar, // 把第 N 个字符改为 X
+}
+
+// 函数说明：解析一行规则
+// 参数：
+// line: 规则文本，规则函数之间的空格可省略
+// 返回：规则，错误信息
+func parseRule(line string) (passwordRule, error) {
+	rule := passwordRule{text: line}
+	runes := []rune(line)
+
+	for i := 0; i < len(runes); {
+		code := runes[i]
+		i++
+		if code == ' ' || code == '\t' {
+			continue
+		}
+		argType, ok := ruleFunctions[code]
+		if !ok {
+			return rule, fmt.Errorf("不支持的规则函数 '%c'", code)
+		}
+
+		op := ruleOp{code: code}
+		need := ruleArgCount[argType]
+		if len(runes)-i < need {
+			return rule, fmt.Errorf("规则函数 '%c' 缺少参数", code)
+		}
+		switch argType {
+		case ruleArgPos:
+			n, err := parseRulePos(runes[i])
+			if err != nil {
+				return rule, err
+			}
+			op.n = n
+		case ruleArgChar:
+			op.x = runes[i]
+		case ruleArgTwoChar:
+			op.x, op.y = runes[i], runes[i+1]
+		case ruleArgPosChar:
+			n, err := parseRulePos(runes[i])
+			if err != nil {
+				return rule, err
+			}
+			op.n, op.x = n, runes[i+1]
+		case ruleArgTwoPos:
+			n, err := parseRulePos(runes[i])
+			if err != nil {
+				return rule, err
+			}
+			m, err := parseRulePos(runes[i+1])
+			if err != nil {
+				return rule, err
+			}
+			op.n, op.m = n, m
+		}
+		i += need
+		rule.ops = append(rule.ops, op)
+	}
+	return rule, nil
+}
+
+// parseRulePos 解析位置参数：0-9 表示 0-9，A-Z 表示 10-35
+func parseRulePos(r rune) (int, error) {
+	switch {
+	case r >= '0' && r <= '9':
+		return int(r - '0'), nil
+	case r >= 'A' && r <= 'Z':
+		return int(r-'A') + 10, nil
+	}
+	return 0, fmt.Errorf("无效的位置参数 '%c'", r)
+}
+
+// 函数说明：对一个密码应用规则
+// 按字符（而不是字节）处理，中文密码反转、截断等不会被截成乱码；位置越界的函数不做改动
+// 参数：
+// word: 原密码
+// 返回：变换后的密码
+func (rule passwordRule) apply(word string) string {
+	w := []rune(word)
+	for _, op := range rule.ops {
+		switch op.code {
+		case 'l':
+			w = []rune(strings.ToLower(string(w)))
+		case 'u':
+			w = []rune(strings.ToUpper(string(w)))
+		case 'c', 'C':
+			first, rest := unicode.ToUpper, unicode.ToLower
+			if op.code == 'C' {
+				first, rest = unicode.ToLower, unicode.ToUpper
+			}
+			for i := range w {
+				if i == 0 {
+					w[i] = first(w[i])
+				} else {
+					w[i] = rest(w[i])
+				}
+			}
+		case 't':
+			for i := range w {
+				w[i] = toggleCase(w[i])
+			}
+		case 'T':
+			if op.n < len(w) {
+				w[op.n] = toggleCase(w[op.n])
+			}
+		case 'r':
+			w = reverseRunes(w)
+		case 'd':
+			w = append(w, w...)
+		case 'p':
+			orig := append([]rune(nil), w...)
+			for i := 0; i < op.n; i++ {
+				w = append(w, orig...)
+			}
+		case 'f':
+			w = append(w, reverseRunes(w)...)
+		case '{':
+			if len(w) > 1 {
+				w = append(w[1:], w[0])
+			}
+		case '}':
+			if len(w) > 1 {
+				w = append([]rune{w[len(w)-1]}, w[:len(w)-1]...)
+			}
+		case '[':
+			if len(w) > 0 {
+				w = w[1:]
+			}
+		case ']':
+			if len(w) > 0 {
+				w = w[:len(w)-1]
+			}
+		case 'D':
+			if op.n < len(w) {
+				w = append(w[:op.n:op.n], w[op.n+1:]...)
+			}
+		case '\'':
+			if op.n < len(w) {
+				w = w[:op.n]
+			}
+		case 'q':
+			doubled := make([]rune, 0, 2*len(w))
+			for _, r := range w {
+				doubled = append(doubled, r, r)
+			}
+			w = doubled
+		case 'z':
+			if len(w) > 0 {
+				w = append(repeatRune(w[0], op.n), w...)
+			}
+		case 'Z':
+			if len(w) > 0 {
+				w = append(w, repeatRune(w[len(w)-1], op.n)...)
+			}
+		case 'y':
+			if op.n <= len(w) {
+				w = append(append([]rune(nil), w[:op.n]...), w...)
+			}
+		case 'Y':
+			if op.n <= len(w) {
+				w = append(w, w[len(w)-op.n:]...)
+			}
+		case 'k':
+			if len(w) > 1 {
+				w[0], w[1] = w[1], w[0]
+			}
+		case 'K':
+			if len(w) > 1 {
+				w[len(w)-1], w[len(w)-2] = w[len(w)-2], w[len(w)-1]
+			}
+		case '*':
+			if op.n < len(w) && op.m < len(w) {
+				w[op.n], w[op.m] = w[op.m], w[op.n]
+			}
+		case 'x':
+			if op.n+op.m <= len(w) {
+				w = w[op.n : op.n+op.m]
+			}
+		case 'O':
+			if op.n+op.m <= len(w) {
+				w = append(w[:op.n:op.n], w[op.n+op.m:]...)
+			}
+		case '+':
+			if op.n < len(w) {
+				w[op.n]++
+			}
+		case '-':
+			if op.n < len(w) {
+				w[op.n]--
+			}
+		case '$':
+			w = append(w, op.x)
+		case '^':
+			w = append([]rune{op.x}, w...)
+		case '@':
+			kept := w[:0:0]
+			for _, r := range w {
+				if r != op.x {
+					kept = append(kept, r)
+				}
+			}
+			w = kept
+		case 's':
+			for i := range w {
+				if w[i] == op.x {
+					w[i] = op.y
+				}
+			}
+		case 'i':
+			if op.n <= len(w) {
+				w = append(w[:op.n:op.n], append([]rune{op.x}, w[op.n:]...)...)
+			}
+		case 'o':
+			if op.n < len(w) {
+				w[op.n] = op.x
+			}
+		}
+	}
+	return string(w)
+}
+
+// isNoop 判断规则是否不改变密码（只含 ':'）
+func (rule passwordRule) isNoop() bool {
+	for _, op := range rule.ops {
+		if op.code != ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// toggleCase 切换字母大小写
+func toggleCase(r rune) rune {
+	if unicode.IsUpper(r) {
+		return unicode.ToLower(r)
+	}
+	return unicode.ToUpper(r)
+}
+
+// repeatRune 返回 n 个 r
+func repeatRune(r rune, n int) []rune {
+	repeated := make([]rune, n)
+	for i := range repeated {
+		repeated[i] = r
+	}
+	return repeated
+}
+
+// reverseRunes 返回反转后的新切片
+func reverseRunes(w []rune) []rune {
+	reversed := make([]rune, len(w))
+	for i, r := range w {
+		reversed[len(w)-1-i] = r
+	}
+	return reversed
+}
+
+// 函数说明：读取规则文件
+// 每行一条规则，空行和 # 开头的注释行忽略；行首尾的空格是规则的一部分（如 "$ " 追加空格），不去掉
+// 参数：
+// path: 规则文件路径
+// 返回：规则列表，无法识别而跳过的行（如 "第 3 行: 不支持的规则函数 'M'"），错误信息
+func readRuleFile(path string) ([]passwordRule, []string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var rules []passwordRule
+	var skipped []string
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseRule(line)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("第 %d 行: %v", lineNo, err))
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("读取规则文件失败: %v", err)
+	}
+	return rules, skipped, nil
+}
+
+// 函数说明：获取所有规则（与 passwd.txt 相同的查找顺序：当前目录、程序目录）
+// 返回：规则列表（按文本去重），使用的规则文件信息，错误信息
+func getAllRules() ([]passwordRule, string, error) {
+	exePath, _ := os.Executable()
+	currentDir, _ := os.Getwd()
+	rulePaths := []string{
+		filepath.Join(currentDir, RULE_FILE_NAME),            // 当前目录
+		filepath.Join(filepath.Dir(exePath), RULE_FILE_NAME), // 程序目录
+	}
+
+	var rules []passwordRule
+	var info string
+	seenPath := make(map[string]bool)
+	seenRule := make(map[string]bool)
+	for _, path := range rulePaths {
+		absPath, err := filepath.Abs(path)
+		if err != nil || seenPath[absPath] {
+			continue
+		}
+		seenPath[absPath] = true
+
+		fileRules, skipped, err := readRuleFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, "", err
+		}
+		info += formatSkippedRules(path, skipped)
+		if len(fileRules) == 0 {
+			continue
+		}
+		for _, rule := range fileRules {
+			// 原样的密码总会先测试一遍，只含 ':' 的规则无需重复
+			if rule.isNoop() {
+				continue
+			}
+			if !seenRule[rule.text] {
+				seenRule[rule.text] = true
+				rules = append(rules, rule)
+			}
+		}
+		info += fmt.Sprintf("使用的规则文件: %s (包含 %d 条规则)\n", path, len(fileRules))
+	}
+	return rules, info, nil
+}
+
+// ruleSkippedPreviewMax 提示中列出的跳过行数上限
+const ruleSkippedPreviewMax = 5
+
+// 函数说明：格式化规则文件中跳过的行
+// 参数：
+// path: 规则文件路径
+// skipped: 跳过的行
+// 返回：提示信息（没有跳过的行时为空）
+func formatSkippedRules(path string, skipped []string) string {
+	if len(skipped) == 0 {
+		return ""
+	}
+	info := fmt.Sprintf("规则文件 %s 中有 %d 行不支持，已跳过:\n", path, len(skipped))
+	for i, line := range skipped {
+		if i >= ruleSkippedPreviewMax {
+			info += fmt.Sprintf("  ...（其余 %d 行未列出）\n", len(skipped)-ruleSkippedPreviewMax)
+			break
+		}
+		info += "  " + line + "\n"
+	}
+	return info
+}
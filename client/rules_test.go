@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRuleFunctions(t *testing.T) {
+	tests := []struct {
+		rule, word, want string
+	}{
+		{":", "password", "password"},
+		{"l", "PassWord", "password"},
+		{"u", "PassWord", "PASSWORD"},
+		{"c", "pASSWORD", "Password"},
+		{"C", "Password", "pASSWORD"},
+		{"t", "PassWord", "pASSwORD"},
+		{"T0", "password", "Password"},
+		{"T9", "password", "password"},
+		{"r", "password", "drowssap"},
+		{"d", "abc", "abcabc"},
+		{"p2", "abc", "abcabcabc"},
+		{"f", "abc", "abccba"},
+		{"{", "password", "asswordp"},
+		{"}", "password", "dpasswor"},
+		{"[", "password", "assword"},
+		{"]", "password", "passwor"},
+		{"D3", "password", "pasword"},
+		{"D9", "password", "password"},
+		{"'4", "password", "pass"},
+		{"q", "abc", "aabbcc"},
+		{"z2", "abc", "aaabc"},
+		{"Z2", "abc", "abccc"},
+		{"y2", "abc", "ababc"},
+		{"y4", "abc", "abc"},
+		{"Y2", "abc", "abcbc"},
+		{"k", "password", "apssword"},
+		{"K", "password", "passwodr"},
+		{"*07", "password", "dassworp"},
+		{"x04", "password", "pass"},
+		{"x48", "password", "password"},
+		{"O12", "password", "psword"},
+		{"+0", "password", "qassword"},
+		{"-1", "password", "p`ssword"},
+		{"$1", "password", "password1"},
+		{"$ ", "password", "password "},
+		{"^!", "password", "!password"},
+		{"^ ", "password", " password"},
+		{"@s", "password", "paword"},
+		{"sa@", "password", "p@ssword"},
+		{"i4-", "password", "pass-word"},
+		{"i8!", "password", "password!"},
+		{"o0P", "password", "Password"},
+		// 组合与中文按字符处理
+		{"c $1 $2 $3", "password", "Password123"},
+		{"sa@so0", "password", "p@ssw0rd"},
+		{"r", "密码123", "321码密"},
+		{"'2", "密码123", "密码"},
+	}
+	for _, tt := range tests {
+		rule, err := parseRule(tt.rule)
+		if err != nil {
+			t.Errorf("解析规则 %q 失败: %v", tt.rule, err)
+			continue
+		}
+		if got := rule.apply(tt.word); got != tt.want {
+			t.Errorf("规则 %q 应用于 %q 得到 %q，应为 %q", tt.rule, tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	for _, line := range []string{"M", "$", "sa", "Ta", "i4", "x0"} {
+		if _, err := parseRule(line); err == nil {
+			t.Errorf("规则 %q 应解析失败", line)
+		}
+	}
+}
+
+// 不支持的行逐行跳过，其余规则照常使用；行首尾的空格不去掉
+func TestReadRuleFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), RULE_FILE_NAME)
+	content := strings.Join([]string{
+		"# 注释",
+		"c",
+		"$ ",
+		"^ ",
+		"",
+		"M",
+		"u $1",
+		"X123",
+		"r",
+	}, "\r\n") + "\r\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, skipped, err := readRuleFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, rule := range rules {
+		texts = append(texts, rule.text)
+	}
+	if got, want := strings.Join(texts, "|"), "c|$ |^ |u $1|r"; got != want {
+		t.Errorf("读出的规则为 %q，应为 %q", got, want)
+	}
+	if len(skipped) != 2 || !strings.HasPrefix(skipped[0], "第 6 行") || !strings.HasPrefix(skipped[1], "第 8 行") {
+		t.Errorf("跳过的行: %q", skipped)
+	}
+	if got := rules[1].apply("abc"); got != "abc " {
+		t.Errorf("\"$ \" 应用于 abc 得到 %q", got)
+	}
+}
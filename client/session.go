@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionSaveInterval 破解进度的保存间隔
+const sessionSaveInterval = 5 * time.Second
+
+// crackSession 破解进度检查点，保存在 7zrpw 临时目录的 sessions 子目录下
+// 以压缩包的大小和前 1MB 的 MD5 识别同一个压缩包（改名、移动后仍能续上），
+// 以候选来源的指纹（字典内容、规则、掩码等设置）确认候选密码没变，
+// 并保存各字典优先尝试的密码的顺序，续跑时恢复，Tested 之前的候选密码都已测试过
+type crackSession struct {
+	ArchivePath     string     `json:"archive_path"`
+	Size            int64      `json:"size"`
+	MD5_1MB         string     `json:"md5_1mb"`
+	DictFingerprint string     `json:"dict_fingerprint"`
+	HotPasswords    [][]string `json:"hot_passwords,omitempty"` // 各字典开始破解时曾经成功过的密码及其顺序
+	Tested          int64      `json:"tested"`
+	Total           int64      `json:"total"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	path string // 检查点文件路径，为空表示无法保存（如无法计算文件指纹）
+}
+
+// getSessionDir 获取破解进度的保存目录
+func getSessionDir() string {
+	return filepath.Join(os.TempDir(), "7zrpw", "sessions")
+}
+
+// 函数说明：加载压缩包的破解进度，没有可续的进度时返回一个新的检查点
+// 参数：
+// archivePath: 压缩文件路径
+// source: 本次使用的候选来源
+// 返回：检查点
+func loadCrackSession(archivePath string, source candidateSource) *crackSession {
+	session := &crackSession{
+		ArchivePath:     archivePath,
+		DictFingerprint: source.fingerprint(),
+		Total:           source.total(),
+	}
+	dicts := candidateDicts(source)
+	for _, dict := range dicts {
+		session.HotPasswords = append(session.HotPasswords, dict.hot)
+	}
+
+	fp, err := getFileFingerprint(archivePath)
+	if err != nil {
+		return session
+	}
+	session.Size = fp.Size
+	session.MD5_1MB = fp.MD5_1MB
+	session.path = filepath.Join(getSessionDir(), fmt.Sprintf("%d_%s.json", fp.Size, fp.MD5_1MB))
+
+	data, err := os.ReadFile(session.path)
+	if err != nil {
+		return session
+	}
+	var saved crackSession
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return session
+	}
+
+	// 字典变了（增删或顺序变化）或规则、掩码等设置变了，旧进度的位置不再对应，重新开始
+	if saved.DictFingerprint != session.DictFingerprint || saved.Tested <= 0 || saved.Tested >= session.Total ||
+		len(saved.HotPasswords) != len(dicts) {
+		return session
+	}
+	// 之后又有密码成功过时优先尝试的顺序会变，恢复上次的顺序，续跑位置才与上次对应
+	for i, dict := range dicts {
+		dict.restoreHot(saved.HotPasswords[i])
+	}
+	session.HotPasswords = saved.HotPasswords
+	session.Tested = saved.Tested
+	session.UpdatedAt = saved.UpdatedAt
+	return session
+}
+
+// 函数说明：询问是否从上次的进度继续
+// 参数：
+// session: 检查点
+// reader: 输入读取器
+// 返回：无（拒绝时把进度清零）
+func offerResumeSession(session *crackSession, reader *bufio.Reader) {
+	if session.Tested <= 0 {
+		return
+	}
+
+	fmt.Printf("\n检测到该文件上次未完成的破解进度: 已测试 %d/%d 个密码（%s）\n",
+		session.Tested, session.Total, session.UpdatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Print("是否从上次的进度继续? (y/n) [Y]: ")
+
+	answer := strings.ToLower(readLineInput(reader))
+	if answer != "" && answer != "y" && answer != "yes" {
+		session.Tested = 0
+		session.remove()
+	}
+}
+
+// save 保存检查点（失败不影响破解）
+func (s *crackSession) save() {
+	if s.path == "" {
+		return
+	}
+	s.UpdatedAt = time.Now()
+	data, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return
+	}
+	// 先写临时文件再改名，避免中途退出留下半个文件
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return
+	}
+	os.Rename(tmpPath, s.path)
+}
+
+// remove 删除检查点（破解结束后不再需要续）
+func (s *crackSession) remove() {
+	if s.path != "" {
+		os.Remove(s.path)
+	}
+}
+
+// crackCheckpoint 多线程下候选密码的完成顺序不定，只把进度推进到第一个尚未完成的位置，
+// 保证续跑时不会漏测
+type crackCheckpoint struct {
+	mu   sync.Mutex
+	next int64          // 在此之前的候选密码都已测试完
+	done map[int64]bool // next 之后已测试完的位置
+}
+
+// newCrackCheckpoint 从 start 位置开始记录
+func newCrackCheckpoint(start int64) *crackCheckpoint {
+	return &crackCheckpoint{next: start, done: make(map[int64]bool)}
+}
+
+// complete 标记第 index 个候选密码已测试完
+func (c *crackCheckpoint) complete(index int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.done[index] = true
+	for c.done[c.next] {
+		delete(c.done, c.next)
+		c.next++
+	}
+}
+
+// position 返回连续测试完的位置
+func (c *crackCheckpoint) position() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.next
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestHits 写入成功记录（程序目录即测试程序所在的临时目录），测试结束后删除
+func writeTestHits(t *testing.T, hits map[string]passwordHit) {
+	t.Helper()
+	data, err := json.Marshal(hits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(getHitsPath(), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(getHitsPath()) })
+}
+
+// drainCandidates 取出候选来源剩余的全部密码
+func drainCandidates(source candidateSource) []string {
+	var passwords []string
+	for {
+		pass, ok := source.next()
+		if !ok {
+			return passwords
+		}
+		passwords = append(passwords, pass)
+	}
+}
+
+// 两次运行之间又有密码成功过（优先尝试的顺序变了），仍从上次的位置继续，且不漏测
+func TestCrackSessionResumeAfterNewHit(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+	archivePath := filepath.Join(dir, "test.7z")
+	if err := os.WriteFile(archivePath, []byte("7z archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dictPath := writeTestDict(t, strings.Fields("p1 p2 p3 p4 p5 p6 p7 p8 p9 p10"))
+	now := time.Now()
+	writeTestHits(t, map[string]passwordHit{"p5": {Count: 1, LastSuccess: now}})
+
+	dict, err := loadPasswordDict([]string{dictPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := drainCandidates(newDictSource(dict, nil))
+	session := loadCrackSession(archivePath, newDictSource(dict, nil))
+	session.Tested = 4
+	session.save()
+
+	// 另一个压缩包用 p8 解压成功
+	writeTestHits(t, map[string]passwordHit{
+		"p5": {Count: 1, LastSuccess: now},
+		"p8": {Count: 2, LastSuccess: now.Add(time.Minute)},
+	})
+	dict, err = loadPasswordDict([]string{dictPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(dict.hot, " ") != "p8 p5" {
+		t.Fatalf("优先尝试的密码为 %q", dict.hot)
+	}
+	source := newDictSource(dict, nil)
+	resumed := loadCrackSession(archivePath, source)
+	if resumed.Tested != 4 {
+		t.Fatalf("续跑位置为 %d，应为 4", resumed.Tested)
+	}
+	skipCandidates(source, resumed.Tested)
+	if got, want := strings.Join(drainCandidates(source), " "), strings.Join(first[4:], " "); got != want {
+		t.Errorf("续跑后的候选密码为 %q，应为 %q", got, want)
+	}
+
+	// 字典内容变了则重新开始
+	if err := os.WriteFile(dictPath, []byte("p1\np2\np3\np4\np5\np6\np7\np8\np9\np10\np11\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dict, err = loadPasswordDict([]string{dictPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed := loadCrackSession(archivePath, newDictSource(dict, nil)); changed.Tested != 0 {
+		t.Errorf("字典变化后续跑位置为 %d，应为 0", changed.Tested)
+	}
+}
@@ -1,3 +1,5 @@
+//go:build windows
+
 package main
 
 import (
@@ -29,9 +31,9 @@ func get7zTempDir() string {
 	return filepath.Join(os.TempDir(), "7zrpw", "7zrpw_"+VERSION)
 }
 
-// 获取7z.exe路径
-func getSevenZipPath() string {
-	return filepath.Join(get7zTempDir(), "7z.exe")
+// newSevenZipBackend Windows 上使用内置的 7z.exe（启动时释放到临时目录，见 init）
+func newSevenZipBackend() *sevenZipBackend {
+	return &sevenZipBackend{path: filepath.Join(get7zTempDir(), "7z.exe"), name: "内置"}
 }
 
 // cleanOld7zVersionDirs 清理旧版本目录，只保留当前版本和上一个版本
@@ -110,8 +112,8 @@ func compareVersionStrings(a, b string) int {
 	return 0
 }
 
-// init 释放内嵌的 7z 组件并预加载 user32.dll
-// 感谢 https://github.com/ShuiJu 的提点：7z.exe/7z.dll 需要时才释放，预加载 user32.dll
+// init 释放内嵌的 7z 组件
+// 感谢 https://github.com/ShuiJu 的提点：7z.exe/7z.dll 需要时才释放，预加载 user32.dll（见 clipboard.go）
 // 按版本号创建子目录，更新后新版本使用新目录，无需每次启动覆盖，不影响启动速度
 func init() {
 	base7zDir := filepath.Join(os.TempDir(), "7zrpw")
@@ -124,7 +126,7 @@ func init() {
 		panic(fmt.Sprintf("无法创建临时目录: %v", err))
 	}
 
-	sevenZipPath := filepath.Join(tempDir, "7z.exe")
+	sevenZipPath := sevenZip.path
 	dllPath := filepath.Join(tempDir, "7z.dll")
 
 	// 提取必要文件（仅当不存在时，同一版本无需重复写入）
@@ -138,9 +140,4 @@ func init() {
 
 	// 清理旧版本目录，只保留当前版本和上一个版本
 	cleanOld7zVersionDirs()
-
-	// 预加载 DLL
-	if err := user32.Load(); err != nil {
-		panic(fmt.Sprintf("无法加载user32.dll: %v", err))
-	}
 }
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package main
+
+import "os/exec"
+
+// SYSTEM_7Z_NAMES 非 Windows 系统上按顺序在 PATH 中查找的 7-Zip 命令：
+// 7zz 为官方 7-Zip，7z 为 p7zip 完整版（支持 RAR），7za 为 p7zip 精简版
+var SYSTEM_7Z_NAMES = []string{"7zz", "7z", "7za"}
+
+// newSevenZipBackend 非 Windows 系统不内置 7z，使用 PATH 中找到的第一个 7-Zip 命令
+// 都找不到时仍按 7z 调用，运行时报告无法启动（RESULT_FAILED），提示安装 7-Zip
+func newSevenZipBackend() *sevenZipBackend {
+	for _, name := range SYSTEM_7Z_NAMES {
+		if path, err := exec.LookPath(name); err == nil {
+			return &sevenZipBackend{path: path, name: "系统"}
+		}
+	}
+	return &sevenZipBackend{path: "7z", name: "系统（未找到 7zz/7z/7za，请安装 7-Zip）"}
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,7 +9,6 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
-	"time"
 )
 
 // clearScreen 清除屏幕内容
@@ -34,7 +33,7 @@ func clearScreen() {
 
 // runInteractive 交互模式主循环：目录浏览、选文件破解、安装/卸载右键菜单等
 func runInteractive() {
-	reader := bufio.NewReader(os.Stdin)
+	reader := stdinReader
 	currentDir := "."
 	for {
 		var archivePath string
@@ -52,6 +51,7 @@ func runInteractive() {
 			// 然后显示其他选项
 
 			fmt.Println("输入a: 解压所有压缩文件")
+			fmt.Println("输入m: 字典+掩码混合破解" + describeHybridState())
 			fmt.Println("输入b: 返回上级目录")
 			fmt.Println("输入i: 安装右键菜单")
 			fmt.Println("输入u: 卸载右键菜单")
@@ -62,21 +62,8 @@ func runInteractive() {
 			// 检查是否有更新消息
 			var choice string
 
-			// 检测右键点击
-			state, _, _ := getAsyncKeyState.Call(uintptr(VK_CONTROL))
-			if state&0x8000 != 0 {
-				// 如果按下了 Ctrl，等待右键点击
-				time.Sleep(100 * time.Millisecond)
-				state, _, _ = getAsyncKeyState.Call(uintptr(WM_RBUTTONDOWN))
-				if state&0x8000 != 0 {
-					// 获取剪贴板内容
-					choice = getClipboardText()
-					fmt.Println(choice) // 显示粘贴的内容
-				}
-			} else {
-				// 普通输入（使用 readLineInput 支持路径中含空格，如拖入文件）
-				choice = readLineInput(reader)
-			}
+			// 普通输入支持路径中含空格（如拖入文件），Ctrl+右键直接粘贴
+			choice = readInputOrPaste(reader)
 
 			if choice == "0" || choice == "q" || choice == "Q" {
 				fmt.Println("程序已退出")
@@ -107,23 +94,19 @@ func runInteractive() {
 					continue
 				}
 
-				// 获取所有密码
-				passwords, passwordsInfo, err := getAllPasswords()
-				if err != nil {
-					fmt.Printf("\n提示：%v\n", err)
-					fmt.Println("将尝试空密码，如果失败可以手动输入密码")
-					passwords = []string{}
-					passwordsInfo = ""
-				}
-
-				fmt.Printf("共 %d 个密码\n\n", len(passwords))
 				fmt.Println("开始尝试破解...")
 
-				// 处理每个压缩文件
+				// 处理每个压缩文件（各文件按文件名选用 passwd.d 中的字典），Ctrl+C 取消后不再处理剩余文件
+				ctx, stop := withInterrupt(context.Background())
 				for i, file := range compressFiles {
+					if ctx.Err() != nil {
+						fmt.Printf("\n已取消: 共 %d 个文件，已处理 %d 个（最后一个被中断），其余 %d 个未处理\n", len(compressFiles), i, len(compressFiles)-i)
+						break
+					}
 					fmt.Printf("\n[%d/%d] 处理文件: %s\n", i+1, len(compressFiles), filepath.Base(file))
-					processArchive(file, passwords, passwordsInfo, reader)
+					processArchive(ctx, file, reader)
 				}
+				stop()
 				// 处理更新和退出
 				handleUpdateAndExit()
 
@@ -141,6 +124,11 @@ func runInteractive() {
 				fmt.Println("1、(推荐)安装右键菜单,通过右键菜单解压文件.")
 				fmt.Println("2、命令行模式: 7zrpw 文件路径,例如: 7zrpw .\\test.zip 或 7zrpw d:\\test\\test.zip")
 				fmt.Println("3、交互模式: 直接双击运行 7zrpw.exe")
+				fmt.Println("4、指定破解线程数: 7zrpw --threads 8 文件路径，或在程序目录的 7zrpw.json 中设置 threads")
+				fmt.Println("5、掩码暴力破解: 7zrpw --mask ?d?d?d?d?d?d 文件路径，或字典破解失败后输入 /mask")
+				fmt.Println("6、字典+掩码混合破解: 7zrpw --hybrid ?d?d?d 文件路径（掩码在左侧用 --hybrid-left），或在交互模式下输入m")
+				fmt.Println("7、字典管理: 7zrpw dict add/remove/dedupe/merge/stats/sort，直接运行 7zrpw dict 查看用法")
+				fmt.Printf("解压后端: %s\n", sevenZip.Version())
 				fmt.Printf("-----------------------------------\n")
 				fmt.Print("右键菜单安装/卸载方法一：\n")
 				fmt.Print("1、右键7zrpw.exe，选择以【管理员身份运行】\n")
@@ -162,6 +150,11 @@ func runInteractive() {
 				// 卸载右键菜单
 				uninstallContext()
 				continue
+			} else if choice == "m" || choice == "M" {
+				clearScreen()
+				// 设置字典+掩码混合破解
+				configureHybridAttack(reader)
+				continue
 			}
 
 			// 尝试解析数字选择
@@ -272,18 +265,11 @@ func runInteractive() {
 			continue
 		}
 
-		// 获取所有密码
-		passwords, passwordsInfo, err := getAllPasswords()
-		if err != nil {
-			fmt.Printf("警告: %v\n", err)
-			passwords = []string{}
-			passwordsInfo = ""
-		}
-
-		fmt.Printf("共 %d 个密码\n\n", len(passwords))
 		fmt.Println("开始尝试破解...")
 
-		processArchive(archivePath, passwords, passwordsInfo, reader)
+		ctx, stop := withInterrupt(context.Background())
+		processArchive(ctx, archivePath, reader)
+		stop()
 		// 处理更新和退出
 		handleUpdateAndExit()
 
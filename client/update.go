@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"crypto/ed25519"
 	"crypto/sha256"
@@ -81,7 +80,7 @@ func asyncCheckUpdate() {
 
 // handleUpdateAndExit 处理更新检查和程序退出
 func handleUpdateAndExit() {
-	reader := bufio.NewReader(os.Stdin)
+	reader := stdinReader
 
 	// 检查是否有更新消息
 	select {
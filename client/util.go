@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -69,6 +70,10 @@ func formatFileSize(size int64) string {
 	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
 }
 
+// stdinReader 控制台输入的共享读取器
+// 所有交互输入都用它读取：各自创建 bufio.Reader 时，先读的会把之后的输入（如粘贴的多行）读进自己的缓冲区
+var stdinReader = bufio.NewReader(os.Stdin)
+
 // 函数说明：读取一行输入（支持路径中含空格，如拖入文件时的路径）
 // 参数：
 // reader: 输入读取器
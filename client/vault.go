@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// 加密密码库（passwd.vault）格式：
+//
+//	文件头：魔数 "7ZRPWVLT"(8) + 版本(1) + PBKDF2 迭代次数(4，大端) + 盐(16)
+//	记录：长度(4，大端) + 随机数(12) + AES-256-GCM 密文
+//
+// 第 0 条记录是固定的校验内容，用于确认主密码；之后每条记录是若干行密码；最后是一条空的结束记录。
+// 每条记录的附加数据为「文件头 + 记录序号 + 是否结束记录」，记录被改动、调换或删除都能发现，
+// 末尾的记录连同结束记录一起被截掉时，因缺少结束记录同样能发现。
+// 新密码以新记录覆盖原结束记录，再写入新的结束记录，无需解密已有内容，也不会在磁盘上留下明文
+const (
+	VAULT_FILE_NAME     = "passwd.vault"
+	vaultMagic          = "7ZRPWVLT"
+	vaultVersion        = 1
+	vaultIterations     = 200000
+	vaultSaltLen        = 16
+	vaultHeaderLen      = len(vaultMagic) + 1 + 4 + vaultSaltLen
+	vaultNonceLen       = 12
+	vaultMaxRecordLen   = 16 << 20 // 单条记录上限，防止损坏的长度字段导致分配过多内存
+	vaultImportChunkLen = 64 << 10 // 导入明文字典时每条记录的大小
+	vaultCheckText      = "7zrpw-vault"
+)
+
+// errVaultIncomplete 密码库缺少结束记录（被截断）
+var errVaultIncomplete = errors.New("密码库不完整")
+
+// passwordVault 已解锁的密码库
+type passwordVault struct {
+	path   string
+	header []byte
+	aead   cipher.AEAD
+}
+
+// 函数说明：用主密码解锁密码库（校验第 0 条记录）
+// 参数：
+// path: 密码库路径
+// passphrase: 主密码
+// 返回：密码库，错误信息
+func unlockVault(path string, passphrase string) (*passwordVault, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header := make([]byte, vaultHeaderLen)
+	if _, err := io.ReadFull(file, header); err != nil || string(header[:len(vaultMagic)]) != vaultMagic {
+		return nil, fmt.Errorf("%s 不是 7zrpw 密码库", path)
+	}
+	if header[len(vaultMagic)] != vaultVersion {
+		return nil, fmt.Errorf("不支持的密码库版本 %d", header[len(vaultMagic)])
+	}
+
+	vault, err := newVault(path, header, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	check, final, err := vault.readRecord(bufio.NewReader(file), 0)
+	if err != nil || final || string(check) != vaultCheckText {
+		return nil, fmt.Errorf("主密码错误")
+	}
+	return vault, nil
+}
+
+// newVault 由文件头和主密码派生密钥
+func newVault(path string, header []byte, passphrase string) (*passwordVault, error) {
+	iterations := int(binary.BigEndian.Uint32(header[len(vaultMagic)+1:]))
+	salt := header[len(vaultMagic)+1+4:]
+	key := pbkdf2.Key([]byte(passphrase), salt, iterations, 32, sha256.New)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &passwordVault{path: path, header: header, aead: aead}, nil
+}
+
+// 函数说明：新建密码库，可同时导入明文字典
+// 参数：
+// path: 密码库路径（已存在时报错）
+// passphrase: 主密码
+// source: 要导入的明文字典（可为 nil）
+// 返回：导入的密码数量，错误信息
+func createVault(path string, passphrase string, source io.Reader) (int, error) {
+	header := make([]byte, 0, vaultHeaderLen)
+	header = append(header, vaultMagic...)
+	header = append(header, vaultVersion)
+	header = binary.BigEndian.AppendUint32(header, vaultIterations)
+	salt := make([]byte, vaultSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return 0, err
+	}
+	header = append(header, salt...)
+
+	vault, err := newVault(path, header, passphrase)
+	if err != nil {
+		return 0, err
+	}
+
+	// 先写临时文件，导入完成后再改名，中途失败不会留下半个密码库
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("创建密码库失败: %v", err)
+	}
+	defer os.Remove(tmpPath)
+
+	w := newVaultWriter(file, vault)
+	count := 0
+	if source != nil {
+		scanner := newPasswordScanner(source)
+		for scanner.Scan() {
+			password := strings.TrimSpace(scanner.Text())
+			if password == "" {
+				continue
+			}
+			w.writePassword(password)
+			count++
+		}
+		if err := scanner.Err(); err != nil {
+			file.Close()
+			return 0, fmt.Errorf("读取明文字典失败: %v", err)
+		}
+	}
+
+	if err := w.finish(); err != nil {
+		file.Close()
+		return 0, fmt.Errorf("写入密码库失败: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return 0, fmt.Errorf("写入密码库失败: %v", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return 0, fmt.Errorf("%s 已存在", path)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return 0, fmt.Errorf("保存密码库失败: %v", err)
+	}
+	return count, nil
+}
+
+// vaultWriter 顺序写出整个密码库：文件头、校验记录，再把密码按块加密成记录，最后是结束记录
+type vaultWriter struct {
+	vault *passwordVault
+	w     *bufio.Writer
+	chunk bytes.Buffer
+	index uint64
+}
+
+// newVaultWriter 写出文件头和第 0 条校验记录
+func newVaultWriter(w io.Writer, vault *passwordVault) *vaultWriter {
+	vw := &vaultWriter{vault: vault, w: bufio.NewWriter(w)}
+	vw.w.Write(vault.header)
+	vw.writeRecord([]byte(vaultCheckText))
+	return vw
+}
+
+func (vw *vaultWriter) writeRecord(plain []byte) {
+	vw.w.Write(vw.vault.sealRecord(plain, vw.index, false))
+	vw.index++
+}
+
+// writePassword 写入一个密码，攒满一块后加密成一条记录
+func (vw *vaultWriter) writePassword(password string) {
+	vw.chunk.WriteString(password + "\n")
+	if vw.chunk.Len() >= vaultImportChunkLen {
+		vw.writeRecord(vw.chunk.Bytes())
+		vw.chunk.Reset()
+	}
+}
+
+// finish 写出最后不满一块的密码和结束记录
+func (vw *vaultWriter) finish() error {
+	if vw.chunk.Len() > 0 {
+		vw.writeRecord(vw.chunk.Bytes())
+		vw.chunk.Reset()
+	}
+	vw.w.Write(vw.vault.sealRecord(nil, vw.index, true))
+	return vw.w.Flush()
+}
+
+// sealRecord 加密一条记录（含长度前缀）
+func (v *passwordVault) sealRecord(plain []byte, index uint64, final bool) []byte {
+	nonce := make([]byte, vaultNonceLen)
+	rand.Read(nonce)
+	sealed := v.aead.Seal(nonce, nonce, plain, v.recordAAD(index, final))
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(sealed))), sealed...)
+}
+
+// recordAAD 记录的附加数据：文件头 + 记录序号 + 是否结束记录
+func (v *passwordVault) recordAAD(index uint64, final bool) []byte {
+	aad := binary.BigEndian.AppendUint64(append([]byte(nil), v.header...), index)
+	if final {
+		return append(aad, 1)
+	}
+	return append(aad, 0)
+}
+
+// readRecord 读取并解密一条记录
+// 返回：明文，是否为结束记录（结束记录之后还有数据视为损坏），错误信息（没有结束记录就到了文件末尾时为 errVaultIncomplete）
+func (v *passwordVault) readRecord(r *bufio.Reader, index uint64) ([]byte, bool, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, false, errVaultIncomplete
+	}
+	n := binary.BigEndian.Uint32(length[:])
+	if n < vaultNonceLen || n > vaultMaxRecordLen {
+		return nil, false, errors.New("密码库已损坏")
+	}
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(r, sealed); err != nil {
+		return nil, false, errVaultIncomplete
+	}
+	nonce, ciphertext := sealed[:vaultNonceLen], sealed[vaultNonceLen:]
+	if plain, err := v.aead.Open(nil, nonce, ciphertext, v.recordAAD(index, false)); err == nil {
+		return plain, false, nil
+	}
+	if _, err := v.aead.Open(nil, nonce, ciphertext, v.recordAAD(index, true)); err == nil {
+		if _, err := r.Peek(1); err != io.EOF {
+			return nil, false, errors.New("密码库已损坏")
+		}
+		return nil, true, nil
+	}
+	return nil, false, errors.New("密码库已损坏")
+}
+
+// vaultReader 边读边解密，按明文逐行提供密码（明文只在内存中）
+type vaultReader struct {
+	vault *passwordVault
+	file  *os.File
+	r     *bufio.Reader
+	index uint64
+	buf   []byte
+	done  bool // 已读到结束记录
+}
+
+// open 打开密码库，读取解密后的明文
+func (v *passwordVault) open() (io.ReadCloser, error) {
+	file, err := os.Open(v.path)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(file)
+	if _, err := r.Discard(vaultHeaderLen); err != nil {
+		file.Close()
+		return nil, errors.New("密码库不完整")
+	}
+	vr := &vaultReader{vault: v, file: file, r: r}
+	// 跳过第 0 条校验记录
+	if _, _, err := v.readRecord(r, 0); err != nil {
+		file.Close()
+		return nil, err
+	}
+	vr.index = 1
+	return vr, nil
+}
+
+func (vr *vaultReader) Read(p []byte) (int, error) {
+	for len(vr.buf) == 0 {
+		if vr.done {
+			return 0, io.EOF
+		}
+		plain, final, err := vr.vault.readRecord(vr.r, vr.index)
+		if err != nil {
+			return 0, err
+		}
+		vr.index++
+		vr.buf = plain
+		vr.done = final
+	}
+	n := copy(p, vr.buf)
+	vr.buf = vr.buf[n:]
+	return n, nil
+}
+
+func (vr *vaultReader) Close() error {
+	return vr.file.Close()
+}
+
+// 函数说明：把一个密码以新记录追加到密码库末尾（已存在时不追加）
+// 新记录覆盖原结束记录的位置，之后再写入新的结束记录
+// 参数：
+// password: 密码
+// 返回：是否追加了，错误信息
+func (v *passwordVault) appendPassword(password string) (bool, error) {
+	// 逐条解密检查是否已存在，同时找到结束记录的序号和位置
+	file, err := os.Open(v.path)
+	if err != nil {
+		return false, err
+	}
+	r := bufio.NewReader(file)
+	r.Discard(vaultHeaderLen)
+	index := uint64(0)
+	offset := int64(vaultHeaderLen)
+	for {
+		plain, final, err := v.readRecord(r, index)
+		if err != nil {
+			file.Close()
+			return false, err
+		}
+		if final {
+			break
+		}
+		if index > 0 {
+			for _, line := range strings.Split(string(plain), "\n") {
+				if strings.TrimSpace(line) == password {
+					file.Close()
+					return false, nil
+				}
+			}
+		}
+		index++
+		offset += int64(4 + vaultNonceLen + len(plain) + v.aead.Overhead())
+	}
+	file.Close()
+
+	f, err := os.OpenFile(v.path, os.O_WRONLY, 0600)
+	if err != nil {
+		return false, fmt.Errorf("打开密码库失败: %v", err)
+	}
+	record := v.sealRecord([]byte(password+"\n"), index, false)
+	record = append(record, v.sealRecord(nil, index+1, true)...)
+	if _, err := f.WriteAt(record, offset); err != nil {
+		f.Close()
+		return false, fmt.Errorf("写入密码库失败: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return false, fmt.Errorf("写入密码库失败: %v", err)
+	}
+	return true, nil
+}
+
+// sealedFileExt 有密码库时，成功记录和已知密码改为加密保存，文件名加上该扩展名（如 passwd_hits.json.enc）
+const sealedFileExt = ".enc"
+
+// sealFile 用密码库的密钥加密整个记录文件：随机数(12) + 密文，附加数据为「文件头 + 文件名」，不同文件的密文不能互换
+func (v *passwordVault) sealFile(name string, plain []byte) []byte {
+	nonce := make([]byte, vaultNonceLen)
+	rand.Read(nonce)
+	return v.aead.Seal(nonce, nonce, plain, append(append([]byte(nil), v.header...), name...))
+}
+
+// openFile 解密 sealFile 加密的记录文件
+func (v *passwordVault) openFile(name string, data []byte) ([]byte, error) {
+	if len(data) < vaultNonceLen {
+		return nil, fmt.Errorf("%s 已损坏", name+sealedFileExt)
+	}
+	plain, err := v.aead.Open(nil, data[:vaultNonceLen], data[vaultNonceLen:], append(append([]byte(nil), v.header...), name...))
+	if err != nil {
+		return nil, fmt.Errorf("%s 已损坏或不属于当前密码库", name+sealedFileExt)
+	}
+	return plain, nil
+}
+
+// 函数说明：读取程序目录中的记录文件（passwd_hits.json、passwd_known.json）
+// 有加密的记录文件时用密码库解密（需要时询问主密码）；还没有时读取以前留下的明文文件，下次保存时转为加密文件
+// 参数：
+// path: 明文记录文件的路径
+// 返回：内容（文件不存在时为 nil），错误信息（如密码库未能解锁）
+func readRecordFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path + sealedFileExt)
+	if os.IsNotExist(err) {
+		data, err = os.ReadFile(path)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return data, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	vault, err := getActiveVault()
+	if err != nil {
+		return nil, err
+	}
+	if vault == nil {
+		return nil, fmt.Errorf("%s 需要用密码库 %s 解密", path+sealedFileExt, VAULT_FILE_NAME)
+	}
+	return vault.openFile(filepath.Base(path), data)
+}
+
+// 函数说明：保存记录文件，先写临时文件再改名，避免中途退出损坏已有记录
+// 有密码库时用密码库的密钥加密后保存到 path+".enc"，并删除以前留下的明文文件，密码不以明文落盘
+// 参数：
+// path: 明文记录文件的路径
+// data: 内容
+// 返回：错误信息
+func writeRecordFile(path string, data []byte) error {
+	vault, err := getActiveVault()
+	if err != nil {
+		return err
+	}
+	target := path
+	if vault != nil {
+		target = path + sealedFileExt
+		data = vault.sealFile(filepath.Base(path), data)
+	}
+	tmpPath := target + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		return err
+	}
+	if vault != nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("删除明文记录 %s 失败: %v", path, err)
+		}
+	}
+	return nil
+}
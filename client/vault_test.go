@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testVaultPassphrase = "correct horse"
+
+// createTestVault 新建导入了 passwords 的密码库
+func createTestVault(t *testing.T, passwords ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), VAULT_FILE_NAME)
+	count, err := createVault(path, testVaultPassphrase, strings.NewReader(strings.Join(passwords, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(passwords) {
+		t.Fatalf("导入 %d 个密码，应为 %d 个", count, len(passwords))
+	}
+	return path
+}
+
+// readVaultPasswords 解锁密码库并读出全部明文
+func readVaultPasswords(path string) (string, error) {
+	vault, err := unlockVault(path, testVaultPassphrase)
+	if err != nil {
+		return "", err
+	}
+	r, err := vault.open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	return string(data), err
+}
+
+func TestVaultRoundTrip(t *testing.T) {
+	path := createTestVault(t, "abc", "密码123")
+
+	vault, err := unlockVault(path, testVaultPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		password string
+		added    bool
+	}{
+		{"new1", true},
+		{"abc", false},
+		{"new2", true},
+		{"new1", false},
+	} {
+		added, err := vault.appendPassword(tt.password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added != tt.added {
+			t.Errorf("追加 %q 的结果为 %v，应为 %v", tt.password, added, tt.added)
+		}
+	}
+
+	got, err := readVaultPasswords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "abc\n密码123\nnew1\nnew2\n"; got != want {
+		t.Errorf("读出 %q，应为 %q", got, want)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "new1") || strings.Contains(string(data), "abc") {
+		t.Error("密码库中出现了明文")
+	}
+}
+
+func TestVaultWrongPassphrase(t *testing.T) {
+	path := createTestVault(t, "abc")
+	if _, err := unlockVault(path, "wrong"); err == nil {
+		t.Fatal("错误的主密码应无法解锁")
+	}
+	if _, err := unlockVault(path, testVaultPassphrase+" "); err == nil {
+		t.Fatal("主密码多了空格也应无法解锁")
+	}
+}
+
+// 记录被改动、调换或删除，以及文件被截断时都应报错，而不是少读出密码
+func TestVaultTamperedAndTruncated(t *testing.T) {
+	path := createTestVault(t, "abc")
+	vault, err := unlockVault(path, testVaultPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 共 4 条记录：校验记录、导入的 abc、追加的 new1、结束记录
+	if _, err := vault.appendPassword("new1"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := splitVaultRecords(t, data)
+	if len(records) != 4 {
+		t.Fatalf("密码库有 %d 条记录，应为 4 条", len(records))
+	}
+	header := data[:vaultHeaderLen]
+	join := func(parts ...[]byte) []byte {
+		out := append([]byte(nil), header...)
+		for _, p := range parts {
+			out = append(out, p...)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"改动记录", func() []byte {
+			d := append([]byte(nil), data...)
+			d[len(d)-len(records[3])-5] ^= 0x01
+			return d
+		}(), nil},
+		{"调换记录", join(records[0], records[2], records[1], records[3]), nil},
+		{"删除中间记录", join(records[0], records[1], records[3]), nil},
+		{"结束记录之后有数据", join(records[0], records[1], records[2], records[3], records[2]), nil},
+		{"删除末尾记录", join(records[0], records[1], records[2]), errVaultIncomplete},
+		{"删除末尾两条记录", join(records[0], records[1]), errVaultIncomplete},
+		{"截断在记录中间", data[:len(data)-10], errVaultIncomplete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, tt.data, 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readVaultPasswords(path)
+			if err == nil {
+				t.Fatalf("应返回错误，读出 %q", got)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("返回 %v，应为 %v", err, tt.want)
+			}
+			if _, err := vault.appendPassword("new2"); err == nil {
+				t.Error("损坏的密码库不应再追加密码")
+			}
+		})
+	}
+}
+
+// splitVaultRecords 按长度前缀拆出文件头之后的各条记录（含长度前缀）
+func splitVaultRecords(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+	var records [][]byte
+	rest := data[vaultHeaderLen:]
+	for len(rest) > 0 {
+		if len(rest) < 4 {
+			t.Fatal("记录长度不完整")
+		}
+		n := 4 + int(binary.BigEndian.Uint32(rest))
+		records = append(records, rest[:n])
+		rest = rest[n:]
+	}
+	return records
+}
+
+// 输入被重定向时从共享的读取器逐行读取主密码，保留首尾空格
+func TestReadDictPassphrase(t *testing.T) {
+	// 在终端中运行测试时 os.Stdin 是终端，换成普通文件以免等待输入
+	stdin, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	saved := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = saved }()
+
+	reader := bufio.NewReader(strings.NewReader(" pass word \r\nsecond\n"))
+	if got := readDictPassphrase(reader, ""); got != " pass word " {
+		t.Errorf("第一行读出 %q", got)
+	}
+	if got := readDictPassphrase(reader, ""); got != "second" {
+		t.Errorf("第二行读出 %q", got)
+	}
+	if got := readDictPassphrase(reader, ""); got != "" {
+		t.Errorf("输入结束后读出 %q", got)
+	}
+}
+
+// 有密码库时成功记录和已知密码加密保存，以前留下的明文记录在下次保存时转为加密文件并删除
+func TestRecordFilesSealedWithVault(t *testing.T) {
+	exePath, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vaultPath := filepath.Join(filepath.Dir(exePath), VAULT_FILE_NAME)
+	if _, err := createVault(vaultPath, testVaultPassphrase, strings.NewReader("abc")); err != nil {
+		t.Fatal(err)
+	}
+	vault, err := unlockVault(vaultPath, testVaultPassphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dictSecretsMu.Lock()
+	unlockedVaults[vaultPath] = vault
+	dictSecretsMu.Unlock()
+	t.Cleanup(func() {
+		dictSecretsMu.Lock()
+		delete(unlockedVaults, vaultPath)
+		dictSecretsMu.Unlock()
+		for _, path := range []string{vaultPath, getHitsPath() + sealedFileExt, getKnownPath(), getKnownPath() + sealedFileExt} {
+			os.Remove(path)
+		}
+	})
+
+	writeTestHits(t, map[string]passwordHit{"legacy-pass": {Count: 3}})
+	if err := recordPasswordHit("secret-pass"); err != nil {
+		t.Fatal(err)
+	}
+	archivePath := filepath.Join(t.TempDir(), "test.7z")
+	if err := os.WriteFile(archivePath, []byte("7z archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordKnownPassword(archivePath, "known-pass"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{getHitsPath(), getKnownPath()} {
+		if fileExists(path) {
+			t.Errorf("留下了明文记录 %s", path)
+		}
+		data, err := os.ReadFile(path + sealedFileExt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, password := range []string{"legacy-pass", "secret-pass", "known-pass"} {
+			if strings.Contains(string(data), password) {
+				t.Errorf("%s 中出现了明文密码 %s", filepath.Base(path)+sealedFileExt, password)
+			}
+		}
+	}
+
+	hits, err := readPasswordHits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits["legacy-pass"].Count != 3 || hits["secret-pass"].Count != 1 {
+		t.Errorf("成功记录为 %v", hits)
+	}
+	known, err := loadKnownArchives()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp, err := getFileFingerprint(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := known[fingerprintKey(fp)].Passwords; len(got) != 1 || got[0] != "known-pass" {
+		t.Errorf("已知密码为 %q", got)
+	}
+
+	// 加密的记录文件不能互换
+	data, err := os.ReadFile(getHitsPath() + sealedFileExt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(getKnownPath()+sealedFileExt, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadKnownArchives(); err == nil {
+		t.Error("互换后的记录文件应无法解密")
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// verifyTimeout 单次密码测试的安全上限，超时只代表「无法确认」，不代表密码正确
+const verifyTimeout = 30 * time.Second
+
+// passwordVerifier 密码验证器
+// 破解流程只依赖该接口，具体由 7z 还是进程内的原生实现验证对调用方透明
+type passwordVerifier interface {
+	// testPassword 返回 RESULT_OK 表示密码正确，RESULT_WRONG_PASSWORD 表示密码错误，
+	// RESULT_UNKNOWN 表示无法确认，其余为与密码无关的压缩包问题
+	testPassword(ctx context.Context, password string) SevenZipResult
+}
+
+// 函数说明：创建密码验证器，能在进程内预检的格式优先使用原生实现
+// 参数：
+// archivePath: 压缩文件路径
+// 返回：验证器
+func newPasswordVerifier(archivePath string) passwordVerifier {
+	switch getFileType(archivePath) {
+	case TYPE_RAR, TYPE_RAR_PART:
+		// RAR5 的密码校验值可直接判定对错，无需启动 7z
+		if v := newRar5Verifier(archivePath); v != nil {
+			return v
+		}
+	case TYPE_ZIP:
+		confirm := newSevenZipVerifier(archivePath)
+		if v := newZipVerifier(archivePath, confirm); v != nil {
+			return v
+		}
+		return confirm
+	case TYPE_7Z:
+		// 文件名加密的 7z：先在进程内解密编码头的第一个块排除错误密码
+		confirm := newSevenZipVerifier(archivePath)
+		if v := newSevenZipAESVerifier(archivePath, confirm); v != nil {
+			return v
+		}
+		return confirm
+	}
+	return newSevenZipVerifier(archivePath)
+}
+
+// archiveEntry 7z l -slt 列出的一个文件
+type archiveEntry struct {
+	Path      string
+	Size      int64
+	IsDir     bool
+	Encrypted bool
+}
+
+// sevenZipVerifier 通过压缩包后端（通常是 7z，未加密的 zip 为纯 Go 后端）判断密码是否正确
+// 创建时先探测一次压缩包：文件名加密的压缩包以能否列出文件为准，
+// 否则只测试最小的一个加密文件，避免每个候选密码都完整测试整个压缩包
+type sevenZipVerifier struct {
+	archivePath     string
+	backend         ArchiveBackend // 创建时选定，避免每个候选密码都重新判断
+	headerEncrypted bool           // 文件名也被加密（如 7z -mhe、rar -hp）
+	testEntry       string         // 用于测试的最小加密文件，为空时测试整个压缩包
+}
+
+// 函数说明：创建 7z 密码验证器
+// 参数：
+// archivePath: 压缩文件路径
+// 返回：验证器
+func newSevenZipVerifier(archivePath string) *sevenZipVerifier {
+	v := &sevenZipVerifier{archivePath: archivePath, backend: selectArchiveBackend(archivePath)}
+
+	entries, result := v.backend.List(context.Background(), archivePath, "")
+	if result != RESULT_OK {
+		// 不带密码无法列出文件：文件名被加密，或压缩包本身有问题（此时退回整包测试，由测试结果报告具体问题）
+		v.headerEncrypted = result == RESULT_WRONG_PASSWORD
+		return v
+	}
+
+	// 挑选最小的非空加密文件：空文件在 7z 中不占数据流，测试它无法区分密码对错
+	var smallest *archiveEntry
+	for i := range entries {
+		e := &entries[i]
+		if e.IsDir || !e.Encrypted || e.Size <= 0 {
+			continue
+		}
+		if smallest == nil || e.Size < smallest.Size {
+			smallest = e
+		}
+	}
+	if smallest != nil {
+		v.testEntry = smallest.Path
+	}
+	return v
+}
+
+// 函数说明：测试密码
+// 参数：
+// ctx: 取消后立即终止 7z 进程（多线程破解时其他线程已找到密码）
+// password: 密码
+// 返回：RESULT_OK 表示密码正确，RESULT_WRONG_PASSWORD 表示密码错误，
+// RESULT_UNKNOWN 表示超过安全上限仍未得出结论，其余为与密码无关的压缩包问题
+func (v *sevenZipVerifier) testPassword(ctx context.Context, password string) SevenZipResult {
+	timeoutCtx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+
+	// 文件名加密的压缩包：能用该密码列出文件即说明密码正确（7z 会校验解密后文件头的 CRC）
+	if v.headerEncrypted {
+		_, result := v.backend.List(timeoutCtx, v.archivePath, password)
+		return result
+	}
+
+	// 测试文件完整性（CRC 校验通过即密码正确），指定文件时只测试该文件
+	return v.backend.Test(timeoutCtx, v.archivePath, password, v.testEntry)
+}
+
+// 函数说明：列出压缩包内的文件
+// 参数：
+// ctx: 上下文（用于超时和取消）
+// archivePath: 压缩文件路径
+// password: 密码（文件名加密时需要）
+// 返回：文件列表，结果类型
+func listArchive(ctx context.Context, archivePath, password string) ([]archiveEntry, SevenZipResult) {
+	return selectArchiveBackend(archivePath).List(ctx, archivePath, password)
+}
+
+// parseSltListing 解析 7z l -slt 的输出：「----------」之后每个文件一段 "键 = 值"，段之间以空行分隔
+func parseSltListing(output string) []archiveEntry {
+	var entries []archiveEntry
+	var current *archiveEntry
+	inFiles := false
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "----------" {
+			inFiles = true
+			continue
+		}
+		if !inFiles {
+			continue
+		}
+		if line == "" {
+			current = nil
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, " = ")
+		if !ok {
+			key, value, ok = strings.Cut(line, " =")
+			if !ok {
+				continue
+			}
+		}
+		if key == "Path" {
+			entries = append(entries, archiveEntry{Path: value})
+			current = &entries[len(entries)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		switch key {
+		case "Size":
+			current.Size, _ = strconv.ParseInt(value, 10, 64)
+		case "Folder":
+			current.IsDir = value == "+"
+		case "Attributes":
+			if strings.HasPrefix(value, "D") {
+				current.IsDir = true
+			}
+		case "Encrypted":
+			current.Encrypted = value == "+"
+		}
+	}
+	return entries
+}
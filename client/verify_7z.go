@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// 7z 格式相关常量（见 7-Zip 源码 DOC/7zFormat.txt）
+const (
+	sz7SignatureHeaderLen  = 32      // 签名头长度
+	sz7MaxEncodedHeader    = 1 << 20 // 编码头的读取上限
+	sz7IDEnd               = 0x00    // kEnd
+	sz7IDHeader            = 0x01    // kHeader
+	sz7IDPackInfo          = 0x06    // kPackInfo
+	sz7IDUnpackInfo        = 0x07    // kUnPackInfo
+	sz7IDSize              = 0x09    // kSize
+	sz7IDCRC               = 0x0A    // kCRC
+	sz7IDFolder            = 0x0B    // kFolder
+	sz7IDEncodedHeader     = 0x17    // kEncodedHeader
+	sz7CoderFlagIDSize     = 0x0F    // 编码器标志：ID 长度
+	sz7CoderFlagComplex    = 0x10    // 编码器标志：多输入/输出流
+	sz7CoderFlagAttrs      = 0x20    // 编码器标志：带属性
+	sz7KdfNoHash           = 0x3F    // AES 属性中表示不做哈希迭代的次数值
+	sz7MaxCyclesPower      = 24      // 可接受的最大迭代次数（2^24）
+	sz7MethodCopy          = "\x00"  // Copy
+	sz7MethodLZMA          = "\x03\x01\x01"
+	sz7MethodLZMA2         = "\x21"
+	sz7MethodAES           = "\x06\xf1\x07\x01"
+	sz7LZMA2ChunkDictReset = 0xE0 // LZMA2 首个 LZMA 块必须重置字典
+	sz7LZMA2CopyDictReset  = 0x01 // LZMA2 首个未压缩块（重置字典）
+)
+
+// sz7Signature 7z 签名
+var sz7Signature = []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}
+
+// sevenZipAESVerifier 进程内 7z（文件名加密 -mhe）密码验证
+// 编码头由 AES-256 加密，密钥由密码按 7z 的 SHA-256 迭代算法派生；
+// 解密编码头的第一个块后按下一个编码器（LZMA/LZMA2/Copy）检查其开头是否合法，
+// 不合法即一定是错误密码，合法的（LZMA 约 1/256 的误判）再交给 7z 确认
+type sevenZipAESVerifier struct {
+	confirm passwordVerifier
+
+	cyclesPower byte
+	salt        []byte
+	iv          [aes.BlockSize]byte
+	block       [aes.BlockSize]byte // 编码头密文的第一个块
+	nextMethod  string              // AES 解密后数据交给的编码器
+}
+
+// 函数说明：创建 7z AES 编码头验证器
+// 参数：
+// archivePath: 压缩文件路径
+// confirm: 通过检查后用于最终确认的验证器
+// 返回：验证器，文件名未加密或编码头结构无法识别时返回 nil
+func newSevenZipAESVerifier(archivePath string, confirm passwordVerifier) *sevenZipAESVerifier {
+	v, err := readSevenZipEncodedHeader(archivePath)
+	if err != nil {
+		return nil
+	}
+	v.confirm = confirm
+	return v
+}
+
+// 函数说明：测试密码
+// 参数：
+// ctx: 上下文（用于取消）
+// password: 密码
+// 返回：结果类型
+func (v *sevenZipAESVerifier) testPassword(ctx context.Context, password string) SevenZipResult {
+	if !utf8.ValidString(password) {
+		// 非 UTF-8 密码无法确定 7z 实际使用的 UTF-16 编码，直接交给 7z
+		return v.confirm.testPassword(ctx, password)
+	}
+
+	cipher, err := aes.NewCipher(deriveSevenZipKey(password, v.salt, v.cyclesPower))
+	if err != nil {
+		return v.confirm.testPassword(ctx, password)
+	}
+	var plain [aes.BlockSize]byte
+	cipher.Decrypt(plain[:], v.block[:])
+	for i := range plain {
+		plain[i] ^= v.iv[i]
+	}
+
+	if !isValidSevenZipStreamStart(v.nextMethod, plain[0]) {
+		return RESULT_WRONG_PASSWORD
+	}
+	return v.confirm.testPassword(ctx, password)
+}
+
+// isValidSevenZipStreamStart 检查 AES 解密后数据的第一个字节是否符合下一个编码器的格式
+func isValidSevenZipStreamStart(method string, first byte) bool {
+	switch method {
+	case sz7MethodLZMA:
+		// LZMA 区间编码器输出的第一个字节恒为 0
+		return first == 0
+	case sz7MethodLZMA2:
+		return first == sz7LZMA2CopyDictReset || first >= sz7LZMA2ChunkDictReset
+	default:
+		// Copy：直接就是文件头
+		return first == sz7IDHeader
+	}
+}
+
+// 函数说明：按 7z 的算法由密码派生 AES-256 密钥
+// 密码按 UTF-16LE 编码，对「盐 + 密码 + 8 字节计数器」重复 2^cyclesPower 次做 SHA-256
+// 参数：
+// password: 密码
+// salt: 盐
+// cyclesPower: 迭代次数（2 的幂）
+// 返回：32 字节密钥
+func deriveSevenZipKey(password string, salt []byte, cyclesPower byte) []byte {
+	pw := utf16.Encode([]rune(password))
+	buf := make([]byte, 0, len(salt)+2*len(pw)+8)
+	buf = append(buf, salt...)
+	for _, c := range pw {
+		buf = binary.LittleEndian.AppendUint16(buf, c)
+	}
+
+	if cyclesPower == sz7KdfNoHash {
+		key := make([]byte, sha256.Size)
+		copy(key, buf)
+		return key
+	}
+
+	counterPos := len(buf)
+	buf = append(buf, make([]byte, 8)...)
+	h := sha256.New()
+	for i := uint64(0); i < 1<<cyclesPower; i++ {
+		binary.LittleEndian.PutUint64(buf[counterPos:], i)
+		h.Write(buf)
+	}
+	return h.Sum(nil)
+}
+
+// 函数说明：读取 7z 编码头的加密参数
+// 参数：
+// archivePath: 压缩文件路径
+// 返回：填好加密参数的验证器（不含 confirm），错误信息
+func readSevenZipEncodedHeader(archivePath string) (*sevenZipAESVerifier, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// 签名头：签名(6) + 版本(2) + 起始头 CRC(4) + 下一个头的偏移(8)、大小(8)、CRC(4)
+	sig := make([]byte, sz7SignatureHeaderLen)
+	if _, err := io.ReadFull(file, sig); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(sig[:6], sz7Signature) {
+		return nil, errors.New("不是 7z 格式")
+	}
+	nextOffset := binary.LittleEndian.Uint64(sig[12:20])
+	nextSize := binary.LittleEndian.Uint64(sig[20:28])
+	nextCRC := binary.LittleEndian.Uint32(sig[28:32])
+	if nextSize == 0 || nextSize > sz7MaxEncodedHeader {
+		return nil, errors.New("7z 头大小无效")
+	}
+
+	header := make([]byte, nextSize)
+	if _, err := file.ReadAt(header, sz7SignatureHeaderLen+int64(nextOffset)); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(header) != nextCRC {
+		return nil, errors.New("7z 头 CRC 校验失败")
+	}
+	if header[0] != sz7IDEncodedHeader {
+		// 文件名未加密
+		return nil, errors.New("7z 头未编码")
+	}
+
+	r := &sz7Reader{data: header[1:]}
+	packPos, err := parseSevenZipPackInfo(r)
+	if err != nil {
+		return nil, err
+	}
+	v, err := parseSevenZipFolder(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := file.ReadAt(v.block[:], sz7SignatureHeaderLen+int64(packPos)); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// parseSevenZipPackInfo 解析 PackInfo，返回第一个打包流的位置（相对签名头之后）
+func parseSevenZipPackInfo(r *sz7Reader) (uint64, error) {
+	if r.byte() != sz7IDPackInfo {
+		return 0, errors.New("7z 编码头缺少 PackInfo")
+	}
+	packPos := r.number()
+	numPackStreams := r.number()
+	for r.err == nil {
+		switch r.byte() {
+		case sz7IDEnd:
+			return packPos, r.err
+		case sz7IDSize:
+			for i := uint64(0); i < numPackStreams && r.err == nil; i++ {
+				r.number()
+			}
+		case sz7IDCRC:
+			r.skipDigests(numPackStreams)
+		default:
+			return 0, errors.New("7z PackInfo 格式无法识别")
+		}
+	}
+	return 0, r.err
+}
+
+// parseSevenZipFolder 解析编码头的 Folder，找到直接作用于打包流的 AES 编码器及其后续编码器
+func parseSevenZipFolder(r *sz7Reader) (*sevenZipAESVerifier, error) {
+	if r.byte() != sz7IDUnpackInfo || r.byte() != sz7IDFolder {
+		return nil, errors.New("7z 编码头缺少 Folder")
+	}
+	if r.number() != 1 || r.byte() != 0 {
+		return nil, errors.New("7z 编码头 Folder 数量无法识别")
+	}
+
+	// 只处理每个编码器都是单输入单输出的常见结构，此时第 i 个输入/输出流即属于第 i 个编码器
+	numCoders := r.number()
+	if numCoders == 0 || numCoders > 4 {
+		return nil, errors.New("7z 编码器数量无法识别")
+	}
+	methods := make([]string, numCoders)
+	props := make([][]byte, numCoders)
+	for i := range methods {
+		flag := r.byte()
+		if flag&sz7CoderFlagComplex != 0 {
+			return nil, errors.New("不支持多流编码器")
+		}
+		methods[i] = string(r.bytes(int(flag & sz7CoderFlagIDSize)))
+		if flag&sz7CoderFlagAttrs != 0 {
+			props[i] = r.bytes(int(r.number()))
+		}
+	}
+
+	// 绑定关系：inIndex 的输入来自 outIndex 的输出；没有被绑定的输入即打包流
+	bound := make([]bool, numCoders)
+	feeds := make(map[uint64]uint64) // 输出编码器 -> 接收它输出的编码器
+	for i := uint64(0); i < numCoders-1; i++ {
+		in, out := r.number(), r.number()
+		if in >= numCoders || out >= numCoders {
+			return nil, errors.New("7z 编码器绑定关系无效")
+		}
+		bound[in] = true
+		feeds[out] = in
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	packed := -1
+	for i, b := range bound {
+		if !b {
+			packed = i
+		}
+	}
+	if packed < 0 || methods[packed] != sz7MethodAES {
+		return nil, errors.New("7z 编码头未加密")
+	}
+
+	v := &sevenZipAESVerifier{nextMethod: sz7MethodCopy}
+	if next, ok := feeds[uint64(packed)]; ok {
+		v.nextMethod = methods[next]
+		if v.nextMethod != sz7MethodLZMA && v.nextMethod != sz7MethodLZMA2 && v.nextMethod != sz7MethodCopy {
+			return nil, errors.New("7z 编码器组合无法识别")
+		}
+	}
+	if err := v.parseAESProps(props[packed]); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// parseAESProps 解析 AES 编码器属性：
+// 字节0 低 6 位为迭代次数，bit7/bit6 表示有盐/IV；字节1 高/低 4 位为盐/IV 的额外长度
+func (v *sevenZipAESVerifier) parseAESProps(props []byte) error {
+	if len(props) < 1 {
+		return errors.New("7z AES 属性缺失")
+	}
+	b0 := props[0]
+	v.cyclesPower = b0 & 0x3F
+	if v.cyclesPower != sz7KdfNoHash && v.cyclesPower > sz7MaxCyclesPower {
+		return errors.New("7z AES 迭代次数无效")
+	}
+	if b0&0xC0 == 0 {
+		return nil
+	}
+	if len(props) < 2 {
+		return errors.New("7z AES 属性不完整")
+	}
+	b1 := props[1]
+	saltSize := int(b0>>7&1) + int(b1>>4)
+	ivSize := int(b0>>6&1) + int(b1&0x0F)
+	if len(props) < 2+saltSize+ivSize || ivSize > aes.BlockSize {
+		return errors.New("7z AES 属性不完整")
+	}
+	v.salt = props[2 : 2+saltSize]
+	copy(v.iv[:], props[2+saltSize:2+saltSize+ivSize])
+	return nil
+}
+
+// sz7Reader 按顺序读取 7z 头中的字段，越界时记录错误并返回零值
+type sz7Reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *sz7Reader) byte() byte {
+	b := r.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *sz7Reader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.data)-r.pos {
+		r.err = errors.New("7z 头字段越界")
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+// number 读取 7z 的变长整数：首字节高位连续 1 的个数表示后续字节数，其余位为最高位部分
+func (r *sz7Reader) number() uint64 {
+	first := r.byte()
+	var value uint64
+	mask := byte(0x80)
+	for i := 0; i < 8; i++ {
+		if first&mask == 0 {
+			high := uint64(first & (mask - 1))
+			return value | high<<(8*i)
+		}
+		value |= uint64(r.byte()) << (8 * i)
+		mask >>= 1
+	}
+	return value
+}
+
+// skipDigests 跳过 CRC 列表：全部定义标志(1)，否则为位图，之后每个已定义项 4 字节
+func (r *sz7Reader) skipDigests(count uint64) {
+	defined := count
+	if r.byte() == 0 {
+		defined = 0
+		bits := r.bytes(int((count + 7) / 8))
+		for i := uint64(0); i < count && bits != nil; i++ {
+			if bits[i/8]&(0x80>>(i%8)) != 0 {
+				defined++
+			}
+		}
+	}
+	r.bytes(int(defined) * 4)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// testdata/7z 中的压缩包只有一个 hello.txt，密码为 secret，由脚本按 7zFormat.txt 写出，
+// 编码器与属性和 7-Zip 相同（文件数据 AES-256 + LZMA，迭代 2^19 次，8 字节 IV，头用 LZMA 编码）：
+// encrypted_headers.7z 对应 7z -mhe=on（编码头也经过 AES），plain_headers.7z 对应默认的 -mhe=off；
+// 两者均已用 libarchive 读取结构、用 Python 解密并解压核对
+func TestSevenZipAESVerifier(t *testing.T) {
+	confirm := &confirmStub{password: "secret"}
+	v := newSevenZipAESVerifier(filepath.Join("testdata", "7z", "encrypted_headers.7z"), confirm)
+	if v == nil {
+		t.Fatal("无法读取加密的编码头")
+	}
+	if v.cyclesPower != 19 || v.nextMethod != sz7MethodLZMA || len(v.salt) != 0 {
+		t.Fatalf("cyclesPower=%d nextMethod=%x salt=%x", v.cyclesPower, v.nextMethod, v.salt)
+	}
+
+	if result := v.testPassword(context.Background(), "secret"); result != RESULT_OK {
+		t.Errorf("正确密码的结果为 %v", getResultDesc(result))
+	}
+	if len(confirm.calls) != 1 {
+		t.Errorf("正确密码应交给 7z 确认一次，实际 %d 次", len(confirm.calls))
+	}
+
+	// LZMA 约有 1/256 的错误密码能通过预检，这几个都应在预检时被排除
+	confirm.calls = nil
+	for _, password := range []string{"wrong", "Secret", "secret1", "", "密码"} {
+		if result := v.testPassword(context.Background(), password); result != RESULT_WRONG_PASSWORD {
+			t.Errorf("错误密码 %q 的结果为 %v", password, getResultDesc(result))
+		}
+	}
+	if len(confirm.calls) != 0 {
+		t.Errorf("错误密码未被预检排除: %q", confirm.calls)
+	}
+}
+
+// 文件名未加密时编码头只有 LZMA，不做预检，全部交给 7z
+func TestSevenZipAESVerifierPlainHeaders(t *testing.T) {
+	confirm := &confirmStub{password: "secret"}
+	if v := newSevenZipAESVerifier(filepath.Join("testdata", "7z", "plain_headers.7z"), confirm); v != nil {
+		t.Fatal("文件名未加密时不应创建预检器")
+	}
+}
+
+// 系统中有 7-Zip 时，用它确认两个压缩包的正确密码和错误密码
+func TestSevenZipVerifierFixtures(t *testing.T) {
+	if _, err := exec.LookPath(getSevenZipPath()); err != nil {
+		t.Skip("未安装 7-Zip")
+	}
+	for _, file := range []string{"encrypted_headers.7z", "plain_headers.7z"} {
+		t.Run(file, func(t *testing.T) {
+			v := newPasswordVerifier(filepath.Join("testdata", "7z", file))
+			if result := v.testPassword(context.Background(), "secret"); result != RESULT_OK {
+				t.Errorf("正确密码的结果为 %v", getResultDesc(result))
+			}
+			if result := v.testPassword(context.Background(), "wrong"); result != RESULT_WRONG_PASSWORD {
+				t.Errorf("错误密码的结果为 %v", getResultDesc(result))
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// RAR5 格式相关常量（见 RARLAB 的 RAR 5.0 archive format 文档）
+const (
+	rar5HeaderMain       = 1       // 主头
+	rar5HeaderFile       = 2       // 文件头
+	rar5HeaderService    = 3       // 服务头
+	rar5HeaderEncryption = 4       // 压缩包加密头（rar -hp，之后的头全部加密）
+	rar5HeaderEnd        = 5       // 结束头
+	rar5FlagExtra        = 0x0001  // 头标志：存在扩展区
+	rar5FlagData         = 0x0002  // 头标志：存在数据区
+	rar5ExtraEncryption  = 0x01    // 文件扩展记录：加密信息
+	rar5EncPswCheck      = 0x0001  // 加密标志：存在密码校验值
+	rar5SaltLen          = 16      // 盐长度
+	rar5IVLen            = 16      // 文件加密 IV 长度
+	rar5CheckLen         = 8       // 密码校验值长度
+	rar5CheckSumLen      = 4       // 密码校验值的校验和长度（SHA-256 前 4 字节）
+	rar5MaxKdfCount      = 24      // KDF 迭代次数上限（2^24）
+	rar5MaxHeaderSize    = 2 << 20 // 单个头的大小上限（2MB）
+	rar5SfxSearchLimit   = 1 << 20 // 自解压文件中查找签名的范围
+	rar5PswCheckRounds   = 32      // 密码校验值在密钥迭代次数基础上多迭代的次数
+	rar5PasswordMaxRunes = 127     // RAR 密码最大长度，超出部分会被截断
+)
+
+// rar5Signature RAR5 签名
+var rar5Signature = []byte("Rar!\x1a\x07\x01\x00")
+
+// rar5Verifier 进程内 RAR5 密码验证
+// RAR5 在加密头（或文件头的加密记录）中保存了由 PBKDF2-HMAC-SHA256 派生的 8 字节密码校验值，
+// 比对校验值即可确定密码对错，无需启动 7z 解压任何数据
+type rar5Verifier struct {
+	archivePath string
+	kdfCount    byte
+	salt        []byte
+	check       []byte
+
+	// 极少数无法在进程内验证的密码（如非 UTF-8）交给 7z
+	fallbackOnce sync.Once
+	fallback     passwordVerifier
+}
+
+// 函数说明：创建 RAR5 密码验证器
+// 参数：
+// archivePath: 压缩文件路径（分卷时为第一个分卷）
+// 返回：验证器，非 RAR5 格式、未加密或没有密码校验值时返回 nil
+func newRar5Verifier(archivePath string) *rar5Verifier {
+	kdfCount, salt, check, err := readRar5PasswordCheck(archivePath)
+	if err != nil {
+		return nil
+	}
+	return &rar5Verifier{
+		archivePath: archivePath,
+		kdfCount:    kdfCount,
+		salt:        salt,
+		check:       check,
+	}
+}
+
+// 函数说明：测试密码
+// 参数：
+// ctx: 上下文（用于取消）
+// password: 密码
+// 返回：RESULT_OK 或 RESULT_WRONG_PASSWORD
+func (v *rar5Verifier) testPassword(ctx context.Context, password string) SevenZipResult {
+	if !utf8.ValidString(password) || utf8.RuneCountInString(password) > rar5PasswordMaxRunes {
+		v.fallbackOnce.Do(func() {
+			v.fallback = newSevenZipVerifier(v.archivePath)
+		})
+		return v.fallback.testPassword(ctx, password)
+	}
+
+	// 校验值 = PBKDF2(密码, 盐, 2^kdfCount+32) 的 32 字节结果按 8 字节异或折叠
+	value := pbkdf2.Key([]byte(password), v.salt, (1<<v.kdfCount)+rar5PswCheckRounds, sha256.Size, sha256.New)
+	check := make([]byte, rar5CheckLen)
+	for i, b := range value {
+		check[i%rar5CheckLen] ^= b
+	}
+	if bytes.Equal(check, v.check) {
+		return RESULT_OK
+	}
+	return RESULT_WRONG_PASSWORD
+}
+
+// 函数说明：读取 RAR5 的密码校验参数
+// 依次遍历各个头：遇到压缩包加密头直接取其校验值；否则取第一个带加密记录的文件头中的校验值
+// 参数：
+// archivePath: 压缩文件路径
+// 返回：KDF 迭代次数（2 的幂），盐，8 字节校验值，错误信息
+func readRar5PasswordCheck(archivePath string) (byte, []byte, []byte, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer file.Close()
+
+	offset, err := findRar5Signature(file)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	fileSize := info.Size()
+	pos := offset + int64(len(rar5Signature))
+
+	for {
+		// 头的开头：CRC32(4) + 头大小(vint，最多 3 字节即可表示 2MB)
+		prefix := make([]byte, 4+3)
+		n, err := file.ReadAt(prefix, pos)
+		if n < 5 && err != nil {
+			return 0, nil, nil, errors.New("RAR5 头不完整")
+		}
+		headerSize, sizeLen := readRar5Vint(prefix[4:n])
+		if sizeLen == 0 || headerSize == 0 || headerSize > rar5MaxHeaderSize {
+			return 0, nil, nil, errors.New("RAR5 头大小无效")
+		}
+
+		header := make([]byte, headerSize)
+		if _, err := file.ReadAt(header, pos+4+int64(sizeLen)); err != nil {
+			return 0, nil, nil, err
+		}
+		r := &rar5Reader{data: header}
+		headerType := r.vint()
+		flags := r.vint()
+		var extraSize, dataSize uint64
+		if flags&rar5FlagExtra != 0 {
+			extraSize = r.vint()
+		}
+		if flags&rar5FlagData != 0 {
+			dataSize = r.vint()
+		}
+		if r.err != nil || extraSize > headerSize {
+			return 0, nil, nil, errors.New("RAR5 头格式无效")
+		}
+
+		switch headerType {
+		case rar5HeaderEncryption:
+			// 压缩包加密头：版本(vint) + 标志(vint) + KDF 次数(1) + 盐(16) + 校验值(12)
+			return parseRar5Check(r, false)
+
+		case rar5HeaderFile:
+			extra := &rar5Reader{data: header[headerSize-extraSize:]}
+			for extra.remaining() > 0 && extra.err == nil {
+				recordSize := extra.vint()
+				record := &rar5Reader{data: extra.bytes(int(recordSize))}
+				if record.vint() != rar5ExtraEncryption {
+					continue
+				}
+				// 文件加密记录：版本(vint) + 标志(vint) + KDF 次数(1) + 盐(16) + IV(16) + 校验值(12)
+				return parseRar5Check(record, true)
+			}
+
+		case rar5HeaderEnd:
+			return 0, nil, nil, errors.New("未找到 RAR5 加密信息")
+		}
+
+		// 数据区不能超出文件末尾，否则位置溢出后可能回到原处而无限循环
+		headerEnd := pos + 4 + int64(sizeLen) + int64(headerSize)
+		if headerEnd > fileSize || dataSize > uint64(fileSize-headerEnd) {
+			return 0, nil, nil, errors.New("RAR5 数据区大小无效")
+		}
+		next := headerEnd + int64(dataSize)
+		if next <= pos {
+			return 0, nil, nil, errors.New("RAR5 头格式无效")
+		}
+		pos = next
+	}
+}
+
+// parseRar5Check 解析加密信息中的 KDF 次数、盐和校验值，并用校验和确认校验值本身完好
+// 参数：
+// r: 指向加密信息「版本」字段的读取器
+// hasIV: 文件加密记录在盐之后还有 16 字节 IV，压缩包加密头没有
+func parseRar5Check(r *rar5Reader, hasIV bool) (byte, []byte, []byte, error) {
+	version := r.vint()
+	encFlags := r.vint()
+	if r.err != nil || version != 0 {
+		// 目前只有版本 0（AES-256）
+		return 0, nil, nil, errors.New("不支持的 RAR5 加密版本")
+	}
+	if encFlags&rar5EncPswCheck == 0 {
+		return 0, nil, nil, errors.New("RAR5 未保存密码校验值")
+	}
+
+	kdfCount := r.bytes(1)
+	salt := r.bytes(rar5SaltLen)
+	if hasIV {
+		r.bytes(rar5IVLen)
+	}
+	check := r.bytes(rar5CheckLen)
+	sum := r.bytes(rar5CheckSumLen)
+	if r.err != nil {
+		return 0, nil, nil, errors.New("RAR5 加密信息不完整")
+	}
+	if kdfCount[0] > rar5MaxKdfCount {
+		return 0, nil, nil, errors.New("RAR5 KDF 迭代次数无效")
+	}
+	digest := sha256.Sum256(check)
+	if !bytes.Equal(digest[:rar5CheckSumLen], sum) {
+		return 0, nil, nil, errors.New("RAR5 密码校验值已损坏")
+	}
+	return kdfCount[0], salt, check, nil
+}
+
+// findRar5Signature 查找 RAR5 签名的位置（普通压缩包在开头，自解压文件在 exe 之后）
+func findRar5Signature(file *os.File) (int64, error) {
+	buf := make([]byte, rar5SfxSearchLimit)
+	n, err := file.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	idx := bytes.Index(buf[:n], rar5Signature)
+	if idx < 0 {
+		return 0, errors.New("不是 RAR5 格式")
+	}
+	return int64(idx), nil
+}
+
+// readRar5Vint 读取 RAR5 变长整数（每字节低 7 位有效，最高位表示后面还有字节）
+// 返回：数值，占用的字节数（0 表示数据不完整）
+func readRar5Vint(data []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < len(data) && i < 10; i++ {
+		value |= uint64(data[i]&0x7f) << (7 * i)
+		if data[i]&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// rar5Reader 按顺序读取头中的字段，越界时记录错误并返回零值
+type rar5Reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *rar5Reader) remaining() int {
+	return len(r.data) - r.pos
+}
+
+func (r *rar5Reader) vint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	value, n := readRar5Vint(r.data[r.pos:])
+	if n == 0 {
+		r.err = errors.New("RAR5 变长整数越界")
+		return 0
+	}
+	r.pos += n
+	return value
+}
+
+func (r *rar5Reader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || n > r.remaining() {
+		r.err = errors.New("RAR5 字段越界")
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ZIP 加密相关常量
+const (
+	zipFlagEncrypted      = 0x1    // 通用标志位 bit0：文件已加密
+	zipFlagDataDescriptor = 0x8    // 通用标志位 bit3：CRC 写在数据之后，校验字节改用修改时间高字节
+	zipMethodAES          = 99     // WinZip AES 的压缩方法号
+	zipExtraAES           = 0x9901 // WinZip AES 扩展字段 ID
+	zipCryptoHeaderLen    = 12     // ZipCrypto 加密头长度
+	zipAESVerifierLen     = 2      // WinZip AES 密码校验值长度
+	zipAESIterations      = 1000   // WinZip AES 的 PBKDF2 迭代次数
+)
+
+// zipVerifier 进程内 ZIP 密码预检（ZipCrypto 与 WinZip AES）
+// ZipCrypto 加密头的最后一字节、WinZip AES 的 2 字节密码校验值都可以直接由密码算出，
+// 不符即一定是错误密码；通过预检的密码（ZipCrypto 约 1/256、AES 约 1/65536 的误判）再交给 7z 确认
+type zipVerifier struct {
+	confirm passwordVerifier
+
+	aes       bool
+	header    []byte // ZipCrypto：12 字节加密头
+	checkByte byte   // ZipCrypto：加密头解密后最后一字节的期望值
+	salt      []byte // AES：盐
+	keyLen    int    // AES：密钥长度（16/24/32）
+	verifier  []byte // AES：2 字节密码校验值
+}
+
+// 函数说明：创建 ZIP 密码预检器
+// 参数：
+// archivePath: 压缩文件路径
+// confirm: 通过预检后用于最终确认的验证器
+// 返回：预检器，无法预检（非标准 ZIP、无加密文件等）时返回 nil
+func newZipVerifier(archivePath string, confirm passwordVerifier) *zipVerifier {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+
+	// 与 7z 验证器一致，选最小的非空加密文件
+	var smallest *zip.File
+	for _, f := range r.File {
+		if f.Flags&zipFlagEncrypted == 0 || f.FileInfo().IsDir() || f.UncompressedSize64 == 0 {
+			continue
+		}
+		if smallest == nil || f.CompressedSize64 < smallest.CompressedSize64 {
+			smallest = f
+		}
+	}
+	if smallest == nil {
+		return nil
+	}
+
+	raw, err := smallest.OpenRaw()
+	if err != nil {
+		return nil
+	}
+
+	v := &zipVerifier{confirm: confirm}
+	if smallest.Method == zipMethodAES {
+		strength, ok := parseZipAESStrength(smallest.Extra)
+		if !ok {
+			return nil
+		}
+		// 强度 1/2/3 对应 AES-128/192/256，盐长度为密钥长度的一半
+		v.aes = true
+		v.keyLen = 8 + 8*strength
+		v.salt = make([]byte, v.keyLen/2)
+		v.verifier = make([]byte, zipAESVerifierLen)
+		if _, err := io.ReadFull(raw, v.salt); err != nil {
+			return nil
+		}
+		if _, err := io.ReadFull(raw, v.verifier); err != nil {
+			return nil
+		}
+		return v
+	}
+
+	v.header = make([]byte, zipCryptoHeaderLen)
+	if _, err := io.ReadFull(raw, v.header); err != nil {
+		return nil
+	}
+	if smallest.Flags&zipFlagDataDescriptor != 0 {
+		v.checkByte = byte(smallest.ModifiedTime >> 8)
+	} else {
+		v.checkByte = byte(smallest.CRC32 >> 24)
+	}
+	return v
+}
+
+// parseZipAESStrength 从扩展字段中解析 WinZip AES 强度（1/2/3）
+func parseZipAESStrength(extra []byte) (int, bool) {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra[0:2])
+		size := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra) < 4+size {
+			return 0, false
+		}
+		// 扩展字段：版本(2) + "AE"(2) + 强度(1) + 实际压缩方法(2)
+		if id == zipExtraAES && size >= 7 {
+			strength := int(extra[4+4])
+			if strength >= 1 && strength <= 3 {
+				return strength, true
+			}
+			return 0, false
+		}
+		extra = extra[4+size:]
+	}
+	return 0, false
+}
+
+// 函数说明：测试密码（预检不通过直接判定错误，通过后交给 7z 确认）
+// 参数：
+// ctx: 上下文（用于取消）
+// password: 密码
+// 返回：结果类型
+func (v *zipVerifier) testPassword(ctx context.Context, password string) SevenZipResult {
+	if !isASCII(password) {
+		// 非 ASCII 密码在 ZIP 中的编码因压缩软件而异（UTF-8/GBK），预检可能误杀，直接交给 7z
+		return v.confirm.testPassword(ctx, password)
+	}
+	if v.aes && !v.checkAES(password) {
+		return RESULT_WRONG_PASSWORD
+	}
+	if !v.aes && !v.checkZipCrypto(password) {
+		return RESULT_WRONG_PASSWORD
+	}
+	return v.confirm.testPassword(ctx, password)
+}
+
+// checkAES 由 PBKDF2-HMAC-SHA1 派生「加密密钥 + 认证密钥 + 2 字节校验值」，比对校验值
+func (v *zipVerifier) checkAES(password string) bool {
+	derived := pbkdf2.Key([]byte(password), v.salt, zipAESIterations, 2*v.keyLen+zipAESVerifierLen, sha1.New)
+	return subtle.ConstantTimeCompare(derived[2*v.keyLen:], v.verifier) == 1
+}
+
+// checkZipCrypto 用传统 PKWARE 加密算法解密 12 字节加密头，比对最后一字节
+func (v *zipVerifier) checkZipCrypto(password string) bool {
+	keys := [3]uint32{0x12345678, 0x23456789, 0x34567890}
+	for i := 0; i < len(password); i++ {
+		zipCryptoUpdateKeys(&keys, password[i])
+	}
+
+	var c byte
+	for _, b := range v.header {
+		temp := keys[2] | 2
+		c = b ^ byte((temp*(temp^1))>>8)
+		zipCryptoUpdateKeys(&keys, c)
+	}
+	return c == v.checkByte
+}
+
+// zipCryptoUpdateKeys 传统 PKWARE 加密的密钥更新
+func zipCryptoUpdateKeys(keys *[3]uint32, b byte) {
+	keys[0] = crc32Update(keys[0], b)
+	keys[1] = (keys[1]+keys[0]&0xff)*134775813 + 1
+	keys[2] = crc32Update(keys[2], byte(keys[1]>>24))
+}
+
+// crc32Update 单字节 CRC32 更新（不做首尾取反，PKWARE 加密要求）
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ (crc >> 8)
+}
+
+// isASCII 判断字符串是否只包含 ASCII 字符
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// confirmStub 代替 7z 的最终确认：记录收到的密码，只认 password
+type confirmStub struct {
+	password string
+	calls    []string
+}
+
+func (c *confirmStub) testPassword(ctx context.Context, password string) SevenZipResult {
+	c.calls = append(c.calls, password)
+	if password == c.password {
+		return RESULT_OK
+	}
+	return RESULT_WRONG_PASSWORD
+}
+
+// testdata/zip 中的压缩包都只有一个 hello.txt（16 字节，存储），密码为 secret：
+// zipcrypto_dd.zip 由 Info-ZIP zip -P 生成（加密时总是使用数据描述符，校验字节为修改时间高字节）；
+// zipcrypto_nodd.zip（校验字节为 CRC 最高字节）与 aes128.zip、aes256.zip（WinZip AE-2）由脚本按规范逐字节写出，
+// ZipCrypto 的那个已用 Python zipfile 解密核对
+func TestZipVerifier(t *testing.T) {
+	tests := []struct {
+		file   string
+		aes    bool
+		keyLen int
+	}{
+		{file: "zipcrypto_dd.zip"},
+		{file: "zipcrypto_nodd.zip"},
+		{file: "aes128.zip", aes: true, keyLen: 16},
+		{file: "aes256.zip", aes: true, keyLen: 32},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			confirm := &confirmStub{password: "secret"}
+			v := newZipVerifier(filepath.Join("testdata", "zip", tt.file), confirm)
+			if v == nil {
+				t.Fatal("无法创建预检器")
+			}
+			if v.aes != tt.aes || v.keyLen != tt.keyLen {
+				t.Fatalf("aes=%v keyLen=%d，应为 aes=%v keyLen=%d", v.aes, v.keyLen, tt.aes, tt.keyLen)
+			}
+
+			if result := v.testPassword(context.Background(), "secret"); result != RESULT_OK {
+				t.Errorf("正确密码的结果为 %v", getResultDesc(result))
+			}
+			if len(confirm.calls) != 1 {
+				t.Errorf("正确密码应交给 7z 确认一次，实际 %d 次", len(confirm.calls))
+			}
+
+			// 这些错误密码在预检时就应被排除，不启动 7z
+			confirm.calls = nil
+			for _, password := range []string{"wrong", "Secret", "secret1", "", "123456"} {
+				if result := v.testPassword(context.Background(), password); result != RESULT_WRONG_PASSWORD {
+					t.Errorf("错误密码 %q 的结果为 %v", password, getResultDesc(result))
+				}
+			}
+			if len(confirm.calls) != 0 {
+				t.Errorf("错误密码未被预检排除: %q", confirm.calls)
+			}
+		})
+	}
+}
+
+// 非 ASCII 密码的编码因压缩软件而异，跳过预检直接确认
+func TestZipVerifierNonASCII(t *testing.T) {
+	confirm := &confirmStub{password: "secret"}
+	v := newZipVerifier(filepath.Join("testdata", "zip", "zipcrypto_nodd.zip"), confirm)
+	if v == nil {
+		t.Fatal("无法创建预检器")
+	}
+	v.testPassword(context.Background(), "密码")
+	if len(confirm.calls) != 1 {
+		t.Errorf("非 ASCII 密码应直接交给 7z 确认")
+	}
+}
@@ -0,0 +1,749 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+	"io"
+)
+
+// xz 解码器：只支持常见的单一 LZMA2 过滤器（xz 默认输出），其他过滤器（BCJ、Delta 等）报告不支持，由 7z 处理
+// 格式见 https://tukaani.org/xz/xz-file-format.txt
+const (
+	xzFilterLZMA2   = 0x21
+	xzMaxDictSize   = 64 << 20 // 超过 xz -9 字典大小（64MB）的交给 7z 解压
+	xzStreamHdrLen  = 12
+	xzStreamFootLen = 12
+)
+
+var (
+	xzMagic       = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	xzFooterMagic = []byte{'Y', 'Z'}
+
+	// errXZUnsupported xz 使用了不支持的过滤器或参数
+	errXZUnsupported = errors.New("不支持的 xz 参数")
+	// errXZCorrupt xz 数据损坏
+	errXZCorrupt = errors.New("xz 数据损坏")
+)
+
+// xzCheckSizes 各校验类型的校验值长度（类型 0x00~0x0F）
+var xzCheckSizes = [16]int{0, 4, 4, 4, 8, 8, 8, 16, 16, 16, 32, 32, 32, 64, 64, 64}
+
+// newXZReader 边读边解压 xz 数据（在后台解码，Close 后停止）
+func newXZReader(r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(xzDecompress(bufio.NewReaderSize(r, 64*1024), pw))
+	}()
+	return pr
+}
+
+// xzReader 统计已读字节数（块和索引的填充按 4 字节对齐计算）
+type xzReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (x *xzReader) ReadByte() (byte, error) {
+	b, err := x.r.ReadByte()
+	if err == nil {
+		x.n++
+	}
+	return b, err
+}
+
+func (x *xzReader) Read(p []byte) (int, error) {
+	n, err := x.r.Read(p)
+	x.n += int64(n)
+	return n, err
+}
+
+// readFull 读满 p，数据提前结束时返回 io.ErrUnexpectedEOF
+func (x *xzReader) readFull(p []byte) error {
+	_, err := io.ReadFull(x, p)
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// vli 读取 xz 变长整数
+func (x *xzReader) vli() (uint64, error) {
+	var value uint64
+	for i := 0; i < 9; i++ {
+		b, err := x.ReadByte()
+		if err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		value |= uint64(b&0x7f) << (7 * i)
+		if b&0x80 == 0 {
+			return value, nil
+		}
+	}
+	return 0, errXZCorrupt
+}
+
+// skipPadding 跳过填充到 4 字节对齐（填充必须为 0）
+func (x *xzReader) skipPadding(size int64) error {
+	for ; size%4 != 0; size++ {
+		b, err := x.ReadByte()
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		if b != 0 {
+			return errXZCorrupt
+		}
+	}
+	return nil
+}
+
+// 函数说明：解压 xz 数据（支持多个流首尾相接）
+// 参数：
+// r: xz 数据
+// w: 解压后的数据写入位置
+// 返回：错误信息（不支持的参数为 errXZUnsupported，数据损坏为 errXZCorrupt 或 io.ErrUnexpectedEOF）
+func xzDecompress(r *bufio.Reader, w io.Writer) error {
+	x := &xzReader{r: r}
+	for first := true; ; first = false {
+		if !first {
+			// 流之间可以有 4 字节倍数的 0 填充
+			for {
+				peek, err := r.Peek(4)
+				if len(peek) == 0 && err == io.EOF {
+					return nil
+				}
+				if len(peek) < 4 {
+					return errXZCorrupt
+				}
+				if !bytes.Equal(peek, []byte{0, 0, 0, 0}) {
+					break
+				}
+				r.Discard(4)
+			}
+		}
+		if err := xzDecodeStream(x, w); err != nil {
+			return err
+		}
+	}
+}
+
+// xzDecodeStream 解压一个流：流头、若干块、索引、流尾
+func xzDecodeStream(x *xzReader, w io.Writer) error {
+	header := make([]byte, xzStreamHdrLen)
+	if err := x.readFull(header); err != nil {
+		return err
+	}
+	if !bytes.Equal(header[:6], xzMagic) {
+		return errXZCorrupt
+	}
+	if crc32.ChecksumIEEE(header[6:8]) != binary.LittleEndian.Uint32(header[8:]) || header[6] != 0 || header[7] > 0x0f {
+		return errXZCorrupt
+	}
+	checkType := header[7]
+
+	for {
+		start := x.n
+		sizeByte, err := x.ReadByte()
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		if sizeByte == 0 {
+			// 索引：块的记录只用于随机访问，顺序解压时校验格式后跳过
+			if err := xzSkipIndex(x, start); err != nil {
+				return err
+			}
+			break
+		}
+		if err := xzDecodeBlock(x, w, (int(sizeByte)+1)*4, checkType); err != nil {
+			return err
+		}
+	}
+
+	footer := make([]byte, xzStreamFootLen)
+	if err := x.readFull(footer); err != nil {
+		return err
+	}
+	if !bytes.Equal(footer[10:], xzFooterMagic) || !bytes.Equal(footer[8:10], header[6:8]) {
+		return errXZCorrupt
+	}
+	return nil
+}
+
+// xzSkipIndex 跳过索引（start 为索引指示字节的位置）
+func xzSkipIndex(x *xzReader, start int64) error {
+	count, err := x.vli()
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < count; i++ {
+		if _, err := x.vli(); err != nil {
+			return err
+		}
+		if _, err := x.vli(); err != nil {
+			return err
+		}
+	}
+	if err := x.skipPadding(x.n - start); err != nil {
+		return err
+	}
+	crc := make([]byte, 4)
+	return x.readFull(crc)
+}
+
+// xzDecodeBlock 解压一个块：块头、LZMA2 数据、填充、校验值
+func xzDecodeBlock(x *xzReader, w io.Writer, headerSize int, checkType byte) error {
+	// 块头至少包含大小、标志、过滤器 ID、属性大小和 CRC32
+	if headerSize < 8 {
+		return errXZCorrupt
+	}
+	header := make([]byte, headerSize)
+	header[0] = byte(headerSize/4 - 1)
+	if err := x.readFull(header[1:]); err != nil {
+		return err
+	}
+	if crc32.ChecksumIEEE(header[:headerSize-4]) != binary.LittleEndian.Uint32(header[headerSize-4:]) {
+		return errXZCorrupt
+	}
+
+	hr := &xzReader{r: bufio.NewReader(bytes.NewReader(header[2 : headerSize-4]))}
+	flags := header[1]
+	if flags&0x03 != 0 {
+		// 多个过滤器（如 BCJ + LZMA2）
+		return errXZUnsupported
+	}
+	if flags&0x40 != 0 {
+		if _, err := hr.vli(); err != nil {
+			return errXZCorrupt
+		}
+	}
+	if flags&0x80 != 0 {
+		if _, err := hr.vli(); err != nil {
+			return errXZCorrupt
+		}
+	}
+	filterID, err := hr.vli()
+	if err != nil {
+		return errXZCorrupt
+	}
+	propsSize, err := hr.vli()
+	if err != nil {
+		return errXZCorrupt
+	}
+	if filterID != xzFilterLZMA2 || propsSize != 1 {
+		return errXZUnsupported
+	}
+	dictProp, err := hr.ReadByte()
+	if err != nil || dictProp > 40 {
+		return errXZCorrupt
+	}
+	dictSize := uint32(0xffffffff)
+	if dictProp < 40 {
+		dictSize = (2 | uint32(dictProp&1)) << (dictProp/2 + 11)
+	}
+	if dictSize > xzMaxDictSize {
+		return errXZUnsupported
+	}
+
+	var check hash.Hash
+	switch checkType {
+	case 0x01:
+		check = crc32.NewIEEE()
+	case 0x04:
+		check = crc64.New(crc64.MakeTable(crc64.ECMA))
+	case 0x0a:
+		check = sha256.New()
+	}
+	out := w
+	if check != nil {
+		out = io.MultiWriter(w, check)
+	}
+
+	dataStart := x.n
+	if err := lzma2Decode(x, out, int(dictSize)); err != nil {
+		return err
+	}
+	if err := x.skipPadding(x.n - dataStart); err != nil {
+		return err
+	}
+	sum := make([]byte, xzCheckSizes[checkType])
+	if err := x.readFull(sum); err != nil {
+		return err
+	}
+	if check != nil {
+		expected := check.Sum(nil)
+		if checkType != 0x0a {
+			// CRC32 与 CRC64 以小端序保存
+			for i, j := 0, len(expected)-1; i < j; i, j = i+1, j-1 {
+				expected[i], expected[j] = expected[j], expected[i]
+			}
+		}
+		if !bytes.Equal(expected, sum) {
+			return errXZCorrupt
+		}
+	}
+	return nil
+}
+
+// LZMA 解码常量
+const (
+	lzmaNumStates       = 12
+	lzmaPosBitsMax      = 4
+	lzmaNumLenToPos     = 4
+	lzmaNumAlignBits    = 4
+	lzmaStartPosModel   = 4
+	lzmaEndPosModel     = 14
+	lzmaNumFullDistance = 1 << (lzmaEndPosModel >> 1)
+	lzmaMatchMinLen     = 2
+	lzmaProbInit        = 1 << 10
+)
+
+// lzmaWindow LZMA 的滑动窗口（字典），解出的字节同时写到输出
+// 窗口随写入的数据增长到 size，声明的字典很大但数据很少时不会预先分配整个字典
+type lzmaWindow struct {
+	buf  []byte
+	size int
+	pos  int
+	full bool
+	out  *bufio.Writer
+}
+
+func (d *lzmaWindow) reset() {
+	d.pos = 0
+	d.full = false
+}
+
+// filled 窗口中已有的字节数
+func (d *lzmaWindow) filled() int {
+	if d.full {
+		return len(d.buf)
+	}
+	return d.pos
+}
+
+func (d *lzmaWindow) putByte(b byte) {
+	if d.pos == len(d.buf) {
+		// 未写满 size 之前不回绕，只需扩大
+		buf := make([]byte, min(max(2*len(d.buf), 64*1024), d.size))
+		copy(buf, d.buf)
+		d.buf = buf
+	}
+	d.buf[d.pos] = b
+	d.pos++
+	if d.pos == d.size {
+		d.pos = 0
+		d.full = true
+	}
+	d.out.WriteByte(b)
+}
+
+// getByte 取之前第 dist 个字节（dist 从 1 开始）
+func (d *lzmaWindow) getByte(dist int) byte {
+	i := d.pos - dist
+	if i < 0 {
+		i += len(d.buf)
+	}
+	return d.buf[i]
+}
+
+// rangeDecoder LZMA 区间解码器
+type rangeDecoder struct {
+	data  []byte
+	pos   int
+	rng   uint32
+	code  uint32
+	error bool
+}
+
+func newRangeDecoder(data []byte) *rangeDecoder {
+	rc := &rangeDecoder{data: data, rng: 0xffffffff}
+	if len(data) < 5 || data[0] != 0 {
+		rc.error = true
+		return rc
+	}
+	rc.code = binary.BigEndian.Uint32(data[1:5])
+	rc.pos = 5
+	return rc
+}
+
+func (rc *rangeDecoder) normalize() {
+	if rc.rng < 1<<24 {
+		rc.rng <<= 8
+		if rc.pos >= len(rc.data) {
+			rc.error = true
+			return
+		}
+		rc.code = rc.code<<8 | uint32(rc.data[rc.pos])
+		rc.pos++
+	}
+}
+
+func (rc *rangeDecoder) bit(prob *uint16) uint32 {
+	bound := (rc.rng >> 11) * uint32(*prob)
+	var bit uint32
+	if rc.code < bound {
+		rc.rng = bound
+		*prob += (1<<11 - *prob) >> 5
+	} else {
+		rc.rng -= bound
+		rc.code -= bound
+		*prob -= *prob >> 5
+		bit = 1
+	}
+	rc.normalize()
+	return bit
+}
+
+func (rc *rangeDecoder) direct(numBits int) uint32 {
+	var result uint32
+	for ; numBits > 0; numBits-- {
+		rc.rng >>= 1
+		rc.code -= rc.rng
+		t := 0 - (rc.code >> 31)
+		rc.code += rc.rng & t
+		result = result<<1 + t + 1
+		rc.normalize()
+	}
+	return result
+}
+
+// bitTree 按位树解码 numBits 位
+func (rc *rangeDecoder) bitTree(probs []uint16, numBits int) uint32 {
+	m := uint32(1)
+	for i := 0; i < numBits; i++ {
+		m = m<<1 + rc.bit(&probs[m])
+	}
+	return m - 1<<numBits
+}
+
+// reverseBitTree 按位树解码 numBits 位（低位在前）
+func (rc *rangeDecoder) reverseBitTree(probs []uint16, numBits int) uint32 {
+	m := uint32(1)
+	var symbol uint32
+	for i := 0; i < numBits; i++ {
+		bit := rc.bit(&probs[m])
+		m = m<<1 + bit
+		symbol |= bit << i
+	}
+	return symbol
+}
+
+// lzmaLenDecoder 匹配长度解码器
+type lzmaLenDecoder struct {
+	choice  uint16
+	choice2 uint16
+	low     [1 << lzmaPosBitsMax][1 << 3]uint16
+	mid     [1 << lzmaPosBitsMax][1 << 3]uint16
+	high    [1 << 8]uint16
+}
+
+func (l *lzmaLenDecoder) reset() {
+	l.choice, l.choice2 = lzmaProbInit, lzmaProbInit
+	for i := range l.low {
+		for j := range l.low[i] {
+			l.low[i][j] = lzmaProbInit
+			l.mid[i][j] = lzmaProbInit
+		}
+	}
+	for i := range l.high {
+		l.high[i] = lzmaProbInit
+	}
+}
+
+// decode 返回长度减去最小匹配长度（0 起）
+func (l *lzmaLenDecoder) decode(rc *rangeDecoder, posState uint32) uint32 {
+	if rc.bit(&l.choice) == 0 {
+		return rc.bitTree(l.low[posState][:], 3)
+	}
+	if rc.bit(&l.choice2) == 0 {
+		return 8 + rc.bitTree(l.mid[posState][:], 3)
+	}
+	return 16 + rc.bitTree(l.high[:], 8)
+}
+
+// lzmaDecoder LZMA 解码状态（LZMA2 的各个块之间延续，按控制字节重置）
+type lzmaDecoder struct {
+	lc, lp, pb   uint32
+	literal      []uint16
+	isMatch      [lzmaNumStates << lzmaPosBitsMax]uint16
+	isRep        [lzmaNumStates]uint16
+	isRepG0      [lzmaNumStates]uint16
+	isRepG1      [lzmaNumStates]uint16
+	isRepG2      [lzmaNumStates]uint16
+	isRep0Long   [lzmaNumStates << lzmaPosBitsMax]uint16
+	posSlot      [lzmaNumLenToPos][1 << 6]uint16
+	posSpecial   [1 + lzmaNumFullDistance - lzmaEndPosModel]uint16
+	align        [1 << lzmaNumAlignBits]uint16
+	lenDecoder   lzmaLenDecoder
+	repDecoder   lzmaLenDecoder
+	state        uint32
+	rep          [4]uint32
+	processedPos uint32
+}
+
+// setProps 设置 lc/lp/pb（LZMA2 要求 lc+lp<=4）
+func (z *lzmaDecoder) setProps(props byte) error {
+	if props >= 9*5*5 {
+		return errXZCorrupt
+	}
+	z.lc = uint32(props % 9)
+	props /= 9
+	z.lp = uint32(props % 5)
+	z.pb = uint32(props / 5)
+	if z.lc+z.lp > 4 {
+		return errXZCorrupt
+	}
+	z.literal = make([]uint16, 0x300<<(z.lc+z.lp))
+	return nil
+}
+
+// resetState 重置概率、状态和重复距离
+func (z *lzmaDecoder) resetState() {
+	for _, probs := range [][]uint16{z.literal, z.isMatch[:], z.isRep[:], z.isRepG0[:], z.isRepG1[:],
+		z.isRepG2[:], z.isRep0Long[:], z.posSpecial[:], z.align[:]} {
+		for i := range probs {
+			probs[i] = lzmaProbInit
+		}
+	}
+	for i := range z.posSlot {
+		for j := range z.posSlot[i] {
+			z.posSlot[i][j] = lzmaProbInit
+		}
+	}
+	z.lenDecoder.reset()
+	z.repDecoder.reset()
+	z.state = 0
+	z.rep = [4]uint32{}
+	z.processedPos = 0
+}
+
+// decodeChunk 解出一个 LZMA2 压缩块中的 size 个字节
+func (z *lzmaDecoder) decodeChunk(rc *rangeDecoder, dict *lzmaWindow, size int) error {
+	pbMask := uint32(1)<<z.pb - 1
+	lpMask := uint32(1)<<z.lp - 1
+	for size > 0 {
+		if rc.error {
+			return errXZCorrupt
+		}
+		posState := z.processedPos & pbMask
+		if rc.bit(&z.isMatch[z.state<<lzmaPosBitsMax+posState]) == 0 {
+			// 字面量
+			var prevByte uint32
+			if dict.filled() > 0 {
+				prevByte = uint32(dict.getByte(1))
+			}
+			base := 0x300 * ((z.processedPos&lpMask)<<z.lc + prevByte>>(8-z.lc))
+			probs := z.literal[base : base+0x300]
+			symbol := uint32(1)
+			if z.state >= 7 {
+				if int(z.rep[0]) >= dict.filled() {
+					return errXZCorrupt
+				}
+				matchByte := uint32(dict.getByte(int(z.rep[0]) + 1))
+				for symbol < 0x100 {
+					matchBit := (matchByte >> 7) & 1
+					matchByte <<= 1
+					bit := rc.bit(&probs[0x100+matchBit<<8+symbol])
+					symbol = symbol<<1 | bit
+					if matchBit != bit {
+						break
+					}
+				}
+			}
+			for symbol < 0x100 {
+				symbol = symbol<<1 | rc.bit(&probs[symbol])
+			}
+			dict.putByte(byte(symbol))
+			z.processedPos++
+			size--
+			switch {
+			case z.state < 4:
+				z.state = 0
+			case z.state < 10:
+				z.state -= 3
+			default:
+				z.state -= 6
+			}
+			continue
+		}
+
+		var length uint32
+		if rc.bit(&z.isRep[z.state]) != 0 {
+			if dict.filled() == 0 {
+				return errXZCorrupt
+			}
+			if rc.bit(&z.isRepG0[z.state]) == 0 {
+				if rc.bit(&z.isRep0Long[z.state<<lzmaPosBitsMax+posState]) == 0 {
+					// 单字节重复
+					if int(z.rep[0]) >= dict.filled() {
+						return errXZCorrupt
+					}
+					if z.state < 7 {
+						z.state = 9
+					} else {
+						z.state = 11
+					}
+					dict.putByte(dict.getByte(int(z.rep[0]) + 1))
+					z.processedPos++
+					size--
+					continue
+				}
+			} else {
+				var dist uint32
+				if rc.bit(&z.isRepG1[z.state]) == 0 {
+					dist = z.rep[1]
+				} else {
+					if rc.bit(&z.isRepG2[z.state]) == 0 {
+						dist = z.rep[2]
+					} else {
+						dist = z.rep[3]
+						z.rep[3] = z.rep[2]
+					}
+					z.rep[2] = z.rep[1]
+				}
+				z.rep[1] = z.rep[0]
+				z.rep[0] = dist
+			}
+			length = z.repDecoder.decode(rc, posState)
+			if z.state < 7 {
+				z.state = 8
+			} else {
+				z.state = 11
+			}
+		} else {
+			z.rep[3], z.rep[2], z.rep[1] = z.rep[2], z.rep[1], z.rep[0]
+			length = z.lenDecoder.decode(rc, posState)
+			if z.state < 7 {
+				z.state = 7
+			} else {
+				z.state = 10
+			}
+			z.rep[0] = z.decodeDistance(rc, length)
+			if z.rep[0] == 0xffffffff {
+				// LZMA2 中不应出现结束标记
+				return errXZCorrupt
+			}
+		}
+
+		n := int(length + lzmaMatchMinLen)
+		if n > size || int(z.rep[0]) >= dict.filled() {
+			return errXZCorrupt
+		}
+		dist := int(z.rep[0]) + 1
+		for i := 0; i < n; i++ {
+			dict.putByte(dict.getByte(dist))
+		}
+		z.processedPos += uint32(n)
+		size -= n
+	}
+	if rc.error {
+		return errXZCorrupt
+	}
+	return nil
+}
+
+// decodeDistance 解码匹配距离（0 起）
+func (z *lzmaDecoder) decodeDistance(rc *rangeDecoder, length uint32) uint32 {
+	lenState := min(length, lzmaNumLenToPos-1)
+	posSlot := rc.bitTree(z.posSlot[lenState][:], 6)
+	if posSlot < lzmaStartPosModel {
+		return posSlot
+	}
+	numDirectBits := int(posSlot>>1) - 1
+	dist := (2 | posSlot&1) << numDirectBits
+	if posSlot < lzmaEndPosModel {
+		return dist + rc.reverseBitTree(z.posSpecial[dist-posSlot:], numDirectBits)
+	}
+	dist += rc.direct(numDirectBits-lzmaNumAlignBits) << lzmaNumAlignBits
+	return dist + rc.reverseBitTree(z.align[:], lzmaNumAlignBits)
+}
+
+// 函数说明：解压一个块的 LZMA2 数据
+// 参数：
+// x: 块数据
+// w: 输出
+// dictSize: 字典大小
+// 返回：错误信息
+func lzma2Decode(x *xzReader, w io.Writer, dictSize int) error {
+	out := bufio.NewWriterSize(w, 64*1024)
+	dict := &lzmaWindow{size: max(dictSize, 4096), out: out}
+	decoder := &lzmaDecoder{}
+	needDictReset, needProps := true, true
+	packed := make([]byte, 1<<16)
+
+	for {
+		control, err := x.ReadByte()
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		if control == 0x00 {
+			return out.Flush()
+		}
+
+		var sizes [4]byte
+		if control == 0x01 || control == 0x02 {
+			// 未压缩的块：0x01 同时重置字典
+			if control == 0x01 {
+				dict.reset()
+				needDictReset = false
+			} else if needDictReset {
+				return errXZCorrupt
+			}
+			if err := x.readFull(sizes[:2]); err != nil {
+				return err
+			}
+			size := int(binary.BigEndian.Uint16(sizes[:2])) + 1
+			if err := x.readFull(packed[:size]); err != nil {
+				return err
+			}
+			for _, b := range packed[:size] {
+				dict.putByte(b)
+			}
+			decoder.processedPos += uint32(size)
+			continue
+		}
+		if control < 0x80 {
+			return errXZCorrupt
+		}
+
+		// LZMA 压缩块：位 5-6 为重置方式（0 不重置，1 重置状态，2 同时设置新参数，3 同时重置字典）
+		reset := (control >> 5) & 0x03
+		if err := x.readFull(sizes[:4]); err != nil {
+			return err
+		}
+		unpackedSize := int(control&0x1f)<<16 + int(binary.BigEndian.Uint16(sizes[:2])) + 1
+		packedSize := int(binary.BigEndian.Uint16(sizes[2:4])) + 1
+		if reset == 3 {
+			dict.reset()
+			needDictReset = false
+		} else if needDictReset {
+			return errXZCorrupt
+		}
+		if reset >= 2 {
+			props, err := x.ReadByte()
+			if err != nil {
+				return io.ErrUnexpectedEOF
+			}
+			if err := decoder.setProps(props); err != nil {
+				return err
+			}
+			needProps = false
+		} else if needProps {
+			return errXZCorrupt
+		}
+		if reset >= 1 {
+			decoder.resetState()
+		}
+
+		if err := x.readFull(packed[:packedSize]); err != nil {
+			return err
+		}
+		if err := decoder.decodeChunk(newRangeDecoder(packed[:packedSize]), dict, unpackedSize); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testdata/xz 中的 .xz 都由 xz 5.6 压缩 sample.bin（可压缩的文本加一段随机字节）得到，参数见文件名：
+// level0/level6/level9e 为 -0/-6/-9e（默认 CRC64），check_* 为 --check=crc32/sha256/none，
+// multiblock 为 --block-size=8KiB（4 个块，块头带大小字段），bcj 为 --x86 --lzma2（不支持的过滤器链）
+
+// readXZTestFile 读取 testdata/xz 中的文件
+func readXZTestFile(t testing.TB, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "xz", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// decompressXZ 用 xzDecompress 解压整段数据
+func decompressXZ(data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	err := xzDecompress(bufio.NewReader(bytes.NewReader(data)), &out)
+	return out.Bytes(), err
+}
+
+func TestXZDecompress(t *testing.T) {
+	sample := readXZTestFile(t, "sample.bin")
+	for _, name := range []string{
+		"level0.xz", "level6.xz", "level9e.xz",
+		"check_crc32.xz", "check_sha256.xz", "check_none.xz",
+		"multiblock.xz",
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := decompressXZ(readXZTestFile(t, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, sample) {
+				t.Errorf("解压结果与 sample.bin 不同（%d 字节，应为 %d 字节）", len(got), len(sample))
+			}
+		})
+	}
+}
+
+// 多个流首尾相接（中间可有 4 字节倍数的 0 填充），如 cat a.xz b.xz
+func TestXZDecompressConcatenated(t *testing.T) {
+	sample := readXZTestFile(t, "sample.bin")
+	var data []byte
+	data = append(data, readXZTestFile(t, "level0.xz")...)
+	data = append(data, readXZTestFile(t, "check_sha256.xz")...)
+	data = append(data, make([]byte, 8)...)
+	data = append(data, readXZTestFile(t, "multiblock.xz")...)
+
+	got, err := decompressXZ(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, bytes.Repeat(sample, 3)) {
+		t.Errorf("解压出 %d 字节，应为 3 份 sample.bin（%d 字节）", len(got), 3*len(sample))
+	}
+
+	// 流之间的填充不是 4 字节的倍数
+	bad := append(append(readXZTestFile(t, "level0.xz"), 0, 0), readXZTestFile(t, "level6.xz")...)
+	if _, err := decompressXZ(bad); !errors.Is(err, errXZCorrupt) {
+		t.Errorf("填充长度错误时返回 %v", err)
+	}
+}
+
+func TestXZDecompressCorrupt(t *testing.T) {
+	data := readXZTestFile(t, "level6.xz")
+	// 单个块的 CRC64 校验值（8 字节）之后是索引（12 字节）和流尾
+	checkOffset := len(data) - xzStreamFootLen - 12 - 8
+
+	tests := []struct {
+		name   string
+		modify func([]byte) []byte
+		want   error
+	}{
+		{"流头魔数", func(d []byte) []byte { d[1] ^= 0xff; return d }, errXZCorrupt},
+		{"块头 CRC", func(d []byte) []byte { d[xzStreamHdrLen+2] ^= 0xff; return d }, errXZCorrupt},
+		{"压缩数据", func(d []byte) []byte { d[len(d)/2] ^= 0x55; return d }, errXZCorrupt},
+		{"校验值", func(d []byte) []byte { d[checkOffset] ^= 0x01; return d }, errXZCorrupt},
+		{"截断", func(d []byte) []byte { return d[:len(d)/2] }, io.ErrUnexpectedEOF},
+		{"缺少流尾", func(d []byte) []byte { return d[:len(d)-6] }, io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decompressXZ(tt.modify(append([]byte(nil), data...)))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("返回 %v，应为 %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// 块头大小字节为 0xFF（块头 1024 字节）时不能按 byte 溢出成 0
+func TestXZDecompressMaxBlockHeaderSize(t *testing.T) {
+	data := append([]byte(nil), xzMagic...)
+	data = append(data, 0x00, 0x04)
+	data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(data[6:8]))
+	data = append(data, 0xff)
+	if _, err := decompressXZ(data); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("返回 %v，应为 io.ErrUnexpectedEOF", err)
+	}
+}
+
+// 字典超过 64MB 的块交给 7z 处理，不在进程内分配窗口（level9e.xz 的字典正好为 64MB）
+func TestXZDecompressLargeDict(t *testing.T) {
+	data := append([]byte(nil), xzMagic...)
+	data = append(data, 0x00, 0x04)
+	data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(data[6:8]))
+	// 块头：大小 12 字节，单个 LZMA2 过滤器，字典属性 29（96MB）
+	header := []byte{0x02, 0x00, xzFilterLZMA2, 0x01, 29, 0x00, 0x00, 0x00}
+	data = append(data, header...)
+	data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE(header))
+	if _, err := decompressXZ(data); !errors.Is(err, errXZUnsupported) {
+		t.Errorf("返回 %v，应为 errXZUnsupported", err)
+	}
+}
+
+// BCJ 等过滤器交给 7z 处理
+func TestXZDecompressUnsupported(t *testing.T) {
+	if _, err := decompressXZ(readXZTestFile(t, "bcj.xz")); !errors.Is(err, errXZUnsupported) {
+		t.Errorf("返回 %v，应为 errXZUnsupported", err)
+	}
+}
+
+func TestXZReader(t *testing.T) {
+	r := newXZReader(bytes.NewReader(readXZTestFile(t, "multiblock.xz")))
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, readXZTestFile(t, "sample.bin")) {
+		t.Error("解压结果与 sample.bin 不同")
+	}
+}
+
+// 任意输入都只能返回错误，不能崩溃或卡住（go test -fuzz=FuzzXZ）
+func FuzzXZ(f *testing.F) {
+	for _, name := range []string{"level0.xz", "check_crc32.xz", "check_none.xz", "multiblock.xz", "bcj.xz"} {
+		f.Add(readXZTestFile(f, name))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		xzDecompress(bufio.NewReader(bytes.NewReader(data)), io.Discard)
+	})
+}
@@ -0,0 +1,178 @@
+// 7zrpw-server 可自建的密码上报与查询服务器
+//
+// 客户端找到密码后以 HS256 JWT 上报（见 client/report.go 的 generateJWT），
+// 开启 server_lookup 后破解前按文件指纹查询。服务器按配置的 app_key 验证签名，
+// 同一个压缩包（文件指纹相同）的记录合并保存，数据放在本地的一个 JSON 文件中。
+//
+// 用法：7zrpw-server [-config 7zrpw-server.json] [-listen :8080]
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 与客户端约定的参数
+const (
+	LOOKUP_ACTION       = "lookup" // params.action 为 lookup 时是查询，否则是上报
+	lookupMaxPasswords  = 20
+	maxPasswordLen      = 256
+	maxNameLen          = 512
+	defaultConfigPath   = "7zrpw-server.json"
+	defaultListenAddr   = ":8080"
+	defaultDataPath     = "7zrpw-server-data.json"
+	serverReadTimeout   = 10 * time.Second
+	serverWriteTimeout  = 10 * time.Second
+	maxRequestBodyBytes = 64 * 1024
+)
+
+var reMD5 = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// ServerConfig 服务器配置
+type ServerConfig struct {
+	Listen  string            `json:"listen"`   // 监听地址，默认 :8080
+	Data    string            `json:"data"`     // 数据文件路径，默认 7zrpw-server-data.json
+	AppKeys map[string]string `json:"app_keys"` // app_key → JWT 签名密钥（客户端的 app_secret）
+}
+
+// server 请求处理
+type server struct {
+	config ServerConfig
+	store  *passwordStore
+}
+
+// 函数说明：读取配置文件
+// 参数：
+// path: 配置文件路径
+// 返回：配置，错误信息
+func loadServerConfig(path string) (ServerConfig, error) {
+	config := ServerConfig{Listen: defaultListenAddr, Data: defaultDataPath}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("解析配置文件失败: %v", err)
+	}
+	if len(config.AppKeys) == 0 {
+		return config, fmt.Errorf("配置文件中没有 app_keys，无法验证客户端")
+	}
+	return config, nil
+}
+
+func main() {
+	configPath := flag.String("config", defaultConfigPath, "配置文件路径")
+	listen := flag.String("listen", "", "监听地址（覆盖配置文件）")
+	flag.Parse()
+
+	config, err := loadServerConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *listen != "" {
+		config.Listen = *listen
+	}
+	store, err := openPasswordStore(config.Data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	s := &server{config: config, store: store}
+	httpServer := &http.Server{
+		Addr:         config.Listen,
+		Handler:      s,
+		ReadTimeout:  serverReadTimeout,
+		WriteTimeout: serverWriteTimeout,
+	}
+	log.Printf("7zrpw-server 监听 %s，已有 %d 个压缩包的记录", config.Listen, store.count())
+	log.Fatal(httpServer.ListenAndServe())
+}
+
+// ServeHTTP 处理上报和查询：POST，Authorization: Bearer <JWT>，参数都在 JWT 的 params 中
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "只接受 POST 请求", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+
+	params, err := s.verifyToken(r.Header.Get("Authorization"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	size, _ := params["size"].(float64)
+	md5_1024, _ := params["md5_1024"].(string)
+	md5_1mb, _ := params["md5_1mb"].(string)
+	if size <= 0 || !reMD5.MatchString(md5_1024) || !reMD5.MatchString(md5_1mb) {
+		http.Error(w, "文件指纹无效", http.StatusBadRequest)
+		return
+	}
+	key := fingerprintKey(int64(size), md5_1024, md5_1mb)
+
+	if action, _ := params["action"].(string); action == LOOKUP_ACTION {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]string{
+			"passwords": s.store.lookup(key, lookupMaxPasswords),
+		})
+		return
+	}
+
+	password, _ := params["password"].(string)
+	name, _ := params["name_raw"].(string)
+	fileType, _ := params["file_type"].(string)
+	if password == "" || len(password) > maxPasswordLen {
+		http.Error(w, "密码无效", http.StatusBadRequest)
+		return
+	}
+	if len(name) > maxNameLen {
+		name = name[:maxNameLen]
+	}
+	if err := s.store.addReport(key, name, fileType, password); err != nil {
+		log.Printf("保存上报失败: %v", err)
+		http.Error(w, "保存失败", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// 函数说明：验证客户端的 JWT：必须是 HS256、未过期，且由配置中 app_key 对应的密钥签名
+// 参数：
+// authorization: Authorization 请求头
+// 返回：JWT 中的 params，错误信息
+func (s *server) verifyToken(authorization string) (map[string]interface{}, error) {
+	tokenString, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return nil, errors.New("缺少 Bearer 令牌")
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		appKey, _ := claims["app_key"].(string)
+		secret, ok := s.config.AppKeys[appKey]
+		if !ok {
+			return nil, fmt.Errorf("未知的 app_key: %q", appKey)
+		}
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, fmt.Errorf("令牌无效: %v", err)
+	}
+
+	params, ok := claims["params"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("令牌中缺少 params")
+	}
+	return params, nil
+}
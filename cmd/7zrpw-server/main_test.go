@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testAppKey    = "test-key"
+	testAppSecret = "test-secret"
+	testMD5_1024  = "0123456789abcdef0123456789abcdef"
+	testMD5_1MB   = "fedcba9876543210fedcba9876543210"
+)
+
+// newTestServer 启动使用临时数据文件的服务器
+func newTestServer(t *testing.T) (*httptest.Server, *server) {
+	t.Helper()
+	store, err := openPasswordStore(filepath.Join(t.TempDir(), defaultDataPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{config: ServerConfig{AppKeys: map[string]string{testAppKey: testAppSecret}}, store: store}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts, s
+}
+
+// testParams 与客户端相同的请求参数，extra 覆盖或追加字段
+func testParams(extra map[string]interface{}) map[string]interface{} {
+	params := map[string]interface{}{
+		"size":      1234,
+		"md5_1024":  testMD5_1024,
+		"md5_1mb":   testMD5_1MB,
+		"name_raw":  "test.7z",
+		"file_type": "7z",
+	}
+	for k, v := range extra {
+		params[k] = v
+	}
+	return params
+}
+
+// signTestToken 按客户端 generateJWT 的格式签发令牌
+func signTestToken(t *testing.T, method jwt.SigningMethod, key interface{}, appKey string, exp time.Time, params map[string]interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.MapClaims{
+		"app_key": appKey,
+		"params":  params,
+		"exp":     exp.Unix(),
+	})
+	tokenString, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokenString
+}
+
+// postToken 以 Bearer 令牌发送 POST 请求，返回状态码和响应内容
+func postToken(t *testing.T, url, tokenString string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokenString != "" {
+		req.Header.Set("Authorization", "Bearer "+tokenString)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+// lookupPasswords 查询测试压缩包的密码
+func lookupPasswords(t *testing.T, url string) []string {
+	t.Helper()
+	tokenString := signTestToken(t, jwt.SigningMethodHS256, []byte(testAppSecret), testAppKey,
+		time.Now().Add(time.Minute), testParams(map[string]interface{}{"action": LOOKUP_ACTION}))
+	status, body := postToken(t, url, tokenString)
+	if status != http.StatusOK {
+		t.Fatalf("查询返回 %d: %s", status, body)
+	}
+	var resp struct {
+		Passwords []string `json:"passwords"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("解析查询结果失败: %v", err)
+	}
+	return resp.Passwords
+}
+
+// reportPassword 上报测试压缩包的密码
+func reportPassword(t *testing.T, url, password string) {
+	t.Helper()
+	tokenString := signTestToken(t, jwt.SigningMethodHS256, []byte(testAppSecret), testAppKey,
+		time.Now().Add(time.Minute), testParams(map[string]interface{}{"password": password}))
+	if status, body := postToken(t, url, tokenString); status != http.StatusOK {
+		t.Fatalf("上报返回 %d: %s", status, body)
+	}
+}
+
+func TestServerRejectsBadTokens(t *testing.T) {
+	ts, s := newTestServer(t)
+	valid := time.Now().Add(time.Minute)
+	params := testParams(map[string]interface{}{"password": "secret"})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"缺少令牌", ""},
+		{"HS512", signTestToken(t, jwt.SigningMethodHS512, []byte(testAppSecret), testAppKey, valid, params)},
+		{"alg none", signTestToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, testAppKey, valid, params)},
+		{"已过期", signTestToken(t, jwt.SigningMethodHS256, []byte(testAppSecret), testAppKey, time.Now().Add(-time.Minute), params)},
+		{"签名密钥错误", signTestToken(t, jwt.SigningMethodHS256, []byte("wrong-secret"), testAppKey, valid, params)},
+		{"未知 app_key", signTestToken(t, jwt.SigningMethodHS256, []byte(testAppSecret), "other-key", valid, params)},
+		{"不是 JWT", "not-a-token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status, body := postToken(t, ts.URL, tt.token); status != http.StatusUnauthorized {
+				t.Errorf("返回 %d，应为 401: %s", status, body)
+			}
+		})
+	}
+
+	// 缺少过期时间
+	noExp, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"app_key": testAppKey,
+		"params":  params,
+	}).SignedString([]byte(testAppSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := postToken(t, ts.URL, noExp); status != http.StatusUnauthorized {
+		t.Errorf("缺少 exp 时返回 %d，应为 401", status)
+	}
+
+	if s.store.count() != 0 {
+		t.Error("无效令牌的上报被保存了")
+	}
+}
+
+func TestServerRejectsBadRequests(t *testing.T) {
+	ts, _ := newTestServer(t)
+	valid := time.Now().Add(time.Minute)
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET 返回 %d，应为 405", resp.StatusCode)
+	}
+
+	for name, params := range map[string]map[string]interface{}{
+		"指纹无效": testParams(map[string]interface{}{"password": "secret", "md5_1mb": "xyz"}),
+		"大小无效": testParams(map[string]interface{}{"password": "secret", "size": 0}),
+		"密码为空": testParams(map[string]interface{}{"password": ""}),
+		"密码过长": testParams(map[string]interface{}{"password": strings.Repeat("x", maxPasswordLen+1)}),
+	} {
+		tokenString := signTestToken(t, jwt.SigningMethodHS256, []byte(testAppSecret), testAppKey, valid, params)
+		if status, _ := postToken(t, ts.URL, tokenString); status != http.StatusBadRequest {
+			t.Errorf("%s: 返回 %d，应为 400", name, status)
+		}
+	}
+}
+
+// 上报后查询：同一个密码只累加次数，上报次数多的在前，最多返回 lookupMaxPasswords 个
+func TestServerReportLookup(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	if got := lookupPasswords(t, ts.URL); len(got) != 0 {
+		t.Fatalf("没有记录时查询到 %q", got)
+	}
+
+	reportPassword(t, ts.URL, "abc")
+	reportPassword(t, ts.URL, "密码123")
+	reportPassword(t, ts.URL, "密码123")
+	if got := strings.Join(lookupPasswords(t, ts.URL), "|"); got != "密码123|abc" {
+		t.Errorf("查询到 %q", got)
+	}
+
+	for i := 0; i < lookupMaxPasswords+5; i++ {
+		reportPassword(t, ts.URL, strings.Repeat("p", i+1))
+	}
+	got := lookupPasswords(t, ts.URL)
+	if len(got) != lookupMaxPasswords {
+		t.Fatalf("查询到 %d 个密码，应为 %d 个", len(got), lookupMaxPasswords)
+	}
+	if got[0] != "密码123" {
+		t.Errorf("第一个密码为 %q", got[0])
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// passwordRecord 一个压缩包的一个密码
+type passwordRecord struct {
+	Password     string    `json:"password"`
+	Count        int       `json:"count"`         // 上报次数
+	LastReported time.Time `json:"last_reported"` // 最近一次上报的时间
+}
+
+// archiveRecord 一个压缩包（按文件指纹去重）的全部密码
+type archiveRecord struct {
+	Name      string            `json:"name"`      // 最近一次上报的文件名，仅供查看
+	FileType  string            `json:"file_type"` // 文件类型描述
+	Passwords []*passwordRecord `json:"passwords"` // 按上报次数排序
+}
+
+// passwordStore 内嵌的密码存储：全部记录放在内存中，每次变动后整体写回一个 JSON 文件
+// 写回时先写同目录的临时文件并落盘，再改名替换，进程中途退出或断电不会损坏已有数据
+type passwordStore struct {
+	mu       sync.RWMutex
+	path     string
+	archives map[string]*archiveRecord // 键为 fingerprintKey
+}
+
+// fingerprintKey 存储中的键：文件大小 + 前 1024 字节和前 1MB 的 MD5（与客户端一致）
+func fingerprintKey(size int64, md5_1024, md5_1mb string) string {
+	return fmt.Sprintf("%d-%s-%s", size, md5_1024, md5_1mb)
+}
+
+// 函数说明：打开存储，文件不存在时从空存储开始
+// 参数：
+// path: 数据文件路径
+// 返回：存储，错误信息
+func openPasswordStore(path string) (*passwordStore, error) {
+	store := &passwordStore{path: path, archives: make(map[string]*archiveRecord)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("读取数据文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &store.archives); err != nil {
+		return nil, fmt.Errorf("解析数据文件失败: %v", err)
+	}
+	return store, nil
+}
+
+// 函数说明：保存一次上报，同一个压缩包的同一个密码只累加次数
+// 参数：
+// key: 文件指纹键
+// name: 文件名
+// fileType: 文件类型描述
+// password: 密码
+// 返回：错误信息
+func (s *passwordStore) addReport(key, name, fileType, password string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	archive := s.archives[key]
+	if archive == nil {
+		archive = &archiveRecord{}
+		s.archives[key] = archive
+	}
+	archive.Name = name
+	archive.FileType = fileType
+
+	var record *passwordRecord
+	for _, r := range archive.Passwords {
+		if r.Password == password {
+			record = r
+			break
+		}
+	}
+	if record == nil {
+		record = &passwordRecord{Password: password}
+		archive.Passwords = append(archive.Passwords, record)
+	}
+	record.Count++
+	record.LastReported = time.Now()
+	sort.SliceStable(archive.Passwords, func(i, j int) bool {
+		return archive.Passwords[i].Count > archive.Passwords[j].Count
+	})
+	return s.save()
+}
+
+// 函数说明：查询压缩包的密码，上报次数多的在前
+// 参数：
+// key: 文件指纹键
+// limit: 最多返回的数量
+// 返回：密码列表（没有记录时为空）
+func (s *passwordStore) lookup(key string, limit int) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	passwords := []string{}
+	if archive := s.archives[key]; archive != nil {
+		for _, r := range archive.Passwords {
+			if len(passwords) >= limit {
+				break
+			}
+			passwords = append(passwords, r.Password)
+		}
+	}
+	return passwords
+}
+
+// save 写回数据文件（调用方持有写锁）
+func (s *passwordStore) save() error {
+	data, err := json.MarshalIndent(s.archives, "", "  ")
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("写入数据文件失败: %v", err)
+	}
+	if err := os.Rename(file.Name(), s.path); err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("替换数据文件失败: %v", err)
+	}
+	return nil
+}
+
+// count 已记录的压缩包数量
+func (s *passwordStore) count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.archives)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 重新打开数据文件后记录不变，写回后不留下临时文件
+func TestPasswordStorePersistence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, defaultDataPath)
+	key := fingerprintKey(1234, testMD5_1024, testMD5_1MB)
+
+	store, err := openPasswordStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, password := range []string{"abc", "密码123", "密码123"} {
+		if err := store.addReport(key, "test.7z", "7z", password); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened, err := openPasswordStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.count() != 1 {
+		t.Errorf("重新打开后有 %d 个压缩包的记录", reopened.count())
+	}
+	if got := strings.Join(reopened.lookup(key, lookupMaxPasswords), "|"); got != "密码123|abc" {
+		t.Errorf("重新打开后查询到 %q", got)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("数据目录中有多余的文件: %q", names)
+	}
+}
+
+// 无法写入时报错，原数据文件保持不变
+func TestPasswordStoreSaveFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, defaultDataPath)
+	key := fingerprintKey(1234, testMD5_1024, testMD5_1MB)
+
+	store, err := openPasswordStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.addReport(key, "test.7z", "7z", "abc"); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 数据文件所在目录不存在时无法创建临时文件
+	store.path = filepath.Join(dir, "missing", defaultDataPath)
+	if err := store.addReport(key, "test.7z", "7z", "def"); err == nil {
+		t.Error("无法写入时应返回错误")
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Error("写入失败后原数据文件被改动")
+	}
+}
+
+// 数据文件损坏时拒绝启动，而不是从空存储开始覆盖掉已有数据
+func TestOpenPasswordStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), defaultDataPath)
+	if err := os.WriteFile(path, []byte("{\"truncated"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openPasswordStore(path); err == nil {
+		t.Error("数据文件损坏时应返回错误")
+	}
+}
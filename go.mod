@@ -13,7 +13,9 @@ require github.com/golang-jwt/jwt/v5 v5.2.1
 require github.com/google/uuid v1.6.0
 
 require (
-	aead.dev/minisign v0.2.0 // indirect
-	github.com/minio/selfupdate v0.6.0 // indirect
-	golang.org/x/crypto v0.0.0-20211209193657-4570a0811e8b // indirect
+	github.com/minio/selfupdate v0.6.0
+	golang.org/x/crypto v0.0.0-20211209193657-4570a0811e8b
+	golang.org/x/term v0.28.0
 )
+
+require aead.dev/minisign v0.2.0 // indirect